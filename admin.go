@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/controllers"
+)
+
+const adminUsage = `Usage:
+  go run . admin create --name "Full Name" --email admin@example.com
+
+The password is read from --password, the ADMIN_PASSWORD environment variable
+or standard input, in that order. The new admin must change it on first login.
+`
+
+// runAdminCommand handles the "admin" subcommand
+func runAdminCommand(args []string) error {
+	if len(args) == 0 || args[0] != "create" {
+		fmt.Fprint(os.Stderr, adminUsage)
+		return fmt.Errorf("unknown admin command")
+	}
+
+	flags := flag.NewFlagSet("admin create", flag.ContinueOnError)
+	fullName := flags.String("name", "", "full name of the admin")
+	email := flags.String("email", "", "email of the admin")
+	password := flags.String("password", "", "initial password of the admin")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	if *password == "" {
+		*password = os.Getenv("ADMIN_PASSWORD")
+	}
+	if *password == "" {
+		fmt.Print("Password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("failed to read password: %v", err)
+		}
+		*password = strings.TrimRight(line, "\r\n")
+	}
+
+	db, err := config.ConnectDB()
+	if err != nil {
+		return err
+	}
+
+	admin, err := controllers.CreateAdminUser(db, *fullName, *email, *password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Admin %s created with ID %d\n", admin.Email, admin.ID)
+	return nil
+}
@@ -0,0 +1,118 @@
+package accounts
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/csvsafe"
+)
+
+// BuildArchive zips the personal data as data.json, adding one CSV file per
+// kind of record when includeCSV is set.
+func BuildArchive(data *PersonalData, includeCSV bool) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	file, err := archive.Create("data.json")
+	if err != nil {
+		return nil, err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return nil, err
+	}
+
+	if includeCSV {
+		tables := csvTables(data)
+		// Sorted so the same data always gives the same archive
+		names := make([]string, 0, len(tables))
+		for name := range tables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			file, err := archive.Create(name)
+			if err != nil {
+				return nil, err
+			}
+			if err := csvsafe.NewWriter(file).WriteAll(tables[name]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func csvTables(data *PersonalData) map[string][][]string {
+	profile := data.Profile
+	tables := map[string][][]string{
+		"profile.csv": {
+			{"id", "full_name", "email", "role", "email_verified_at", "two_factor_enabled", "created_at"},
+			{formatUint(profile.ID), profile.FullName, profile.Email, profile.Role, formatTime(profile.EmailVerifiedAt), strconv.FormatBool(profile.TwoFactorEnabled), formatTime(&profile.CreatedAt)},
+		},
+		"tasks.csv":         {{"id", "title", "description", "status", "category_id", "owner", "assignee_id", "due_date", "created_at"}},
+		"comments.csv":      {{"id", "task_id", "body", "created_at"}},
+		"notifications.csv": {{"id", "type", "message", "task_id", "read_at", "created_at"}},
+		"activity.csv":      {{"type", "result", "detail", "ip", "created_at"}},
+		"time_entries.csv":  {{"id", "task_id", "started_at", "ended_at", "note"}},
+	}
+
+	for _, task := range data.Tasks {
+		tables["tasks.csv"] = append(tables["tasks.csv"], []string{
+			formatUint(task.ID), task.Title, task.Description, strconv.FormatBool(task.Status), formatUint(task.CategoryID),
+			strconv.FormatBool(task.Owner), formatOptionalUint(task.AssigneeID), formatTime(task.DueDate), formatTime(&task.CreatedAt),
+		})
+	}
+	for _, comment := range data.Comments {
+		tables["comments.csv"] = append(tables["comments.csv"], []string{
+			formatUint(comment.ID), formatUint(comment.TaskID), comment.Body, formatTime(&comment.CreatedAt),
+		})
+	}
+	for _, notification := range data.Notifications {
+		tables["notifications.csv"] = append(tables["notifications.csv"], []string{
+			formatUint(notification.ID), notification.Type, notification.Message, formatOptionalUint(notification.TaskID),
+			formatTime(notification.ReadAt), formatTime(&notification.CreatedAt),
+		})
+	}
+	for _, activity := range data.Activity {
+		tables["activity.csv"] = append(tables["activity.csv"], []string{
+			activity.Type, activity.Result, activity.Detail, activity.IP, formatTime(&activity.CreatedAt),
+		})
+	}
+	for _, entry := range data.TimeEntries {
+		tables["time_entries.csv"] = append(tables["time_entries.csv"], []string{
+			formatUint(entry.ID), formatUint(entry.TaskID), formatTime(&entry.StartedAt), formatTime(entry.EndedAt), entry.Note,
+		})
+	}
+
+	return tables
+}
+
+func formatUint(n uint) string {
+	return fmt.Sprint(n)
+}
+
+func formatOptionalUint(n *uint) string {
+	if n == nil {
+		return ""
+	}
+	return formatUint(*n)
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,29 @@
+package accounts
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBuildArchiveOrdersFiles(t *testing.T) {
+	want := []string{"data.json", "activity.csv", "comments.csv", "notifications.csv", "profile.csv", "tasks.csv", "time_entries.csv"}
+	for i := 0; i < 5; i++ {
+		archive, err := BuildArchive(&PersonalData{}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, file := range reader.File {
+			names = append(names, file.Name)
+		}
+		if !reflect.DeepEqual(names, want) {
+			t.Fatalf("archive files are %v, want %v", names, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package accounts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// What happens to the tasks a deleted user owns
+const (
+	TasksTransfer  = "transfer"
+	TasksAnonymize = "anonymize"
+	TasksDelete    = "delete"
+)
+
+// DeletedUserEmail is the email of the placeholder user that takes over
+// anonymized tasks and the comments of deleted users. Users cannot take
+// .invalid addresses, and the placeholder is found by its Placeholder flag
+// rather than by email.
+const DeletedUserEmail = "deleted-user@users.invalid"
+
+// DeletionGracePeriod is how long a deleted account can still be restored,
+// set with ACCOUNT_DELETION_GRACE_DAYS (default 14)
+var DeletionGracePeriod = gracePeriodFromEnv()
+
+func gracePeriodFromEnv() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("ACCOUNT_DELETION_GRACE_DAYS"))
+	if err != nil || days < 0 {
+		days = 14
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func ValidTaskAction(action string) bool {
+	return action == TasksTransfer || action == TasksAnonymize || action == TasksDelete
+}
+
+// ScheduleDeletion deactivates the account and logs it out everywhere except
+// keepSessionID, which can still be used to download a data export. The
+// account is purged once the grace period is over unless it is restored.
+func ScheduleDeletion(db *gorm.DB, user *models.User, action string, transferToID *uint, keepSessionID string) error {
+	deleteAt := time.Now().Add(DeletionGracePeriod)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"deletion_scheduled_at":   deleteAt,
+			"deletion_task_action":    action,
+			"deletion_transfer_to_id": transferToID,
+		}).Error; err != nil {
+			return err
+		}
+		return config.RevokeSessions(tx, user.ID, keepSessionID)
+	})
+	if err != nil {
+		return err
+	}
+
+	user.DeletionScheduledAt = &deleteAt
+	user.DeletionTaskAction = action
+	user.DeletionTransferToID = transferToID
+	return nil
+}
+
+// Restore cancels a scheduled deletion
+func Restore(db *gorm.DB, user *models.User) error {
+	if err := db.Model(user).Updates(map[string]interface{}{
+		"deletion_scheduled_at":   nil,
+		"deletion_task_action":    "",
+		"deletion_transfer_to_id": nil,
+	}).Error; err != nil {
+		return err
+	}
+
+	user.DeletionScheduledAt = nil
+	user.DeletionTaskAction = ""
+	user.DeletionTransferToID = nil
+	return nil
+}
+
+// deletedUser returns the placeholder user, creating it the first time. It
+// is disabled and its password is not a valid hash, so nobody can log in.
+func deletedUser(tx *gorm.DB) (models.User, error) {
+	// Placeholders created before the flag are known by that password, which
+	// no user can set
+	if err := tx.Model(&models.User{}).Where("placeholder = ? AND email = ? AND password = ?", false, DeletedUserEmail, "!").Update("placeholder", true).Error; err != nil {
+		return models.User{}, err
+	}
+
+	now := time.Now()
+	user := models.User{
+		FullName:    "Deleted user",
+		Email:       DeletedUserEmail,
+		Password:    "!",
+		Role:        "member",
+		DisabledAt:  &now,
+		Placeholder: true,
+	}
+	err := tx.Where("placeholder = ?", true).First(&user).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	// Someone registered the address before .invalid ones were refused
+	var taken int64
+	if err := tx.Model(&models.User{}).Where("LOWER(email) = ?", DeletedUserEmail).Count(&taken).Error; err != nil {
+		return user, err
+	}
+	if taken > 0 {
+		user.Email = fmt.Sprintf("deleted-user-%d@users.invalid", now.UnixNano())
+	}
+	err = tx.Create(&user).Error
+	return user, err
+}
+
+// Purge deletes a user for good. Their tasks are transferred, anonymized or
+// deleted as they chose, and their comments on remaining tasks are kept
+// under the placeholder user.
+func Purge(db *gorm.DB, user models.User) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		placeholder, err := deletedUser(tx)
+		if err != nil {
+			return err
+		}
+
+		action := user.DeletionTaskAction
+		var newOwnerID uint
+		switch action {
+		case TasksTransfer:
+			var target models.User
+			if user.DeletionTransferToID != nil && tx.First(&target, *user.DeletionTransferToID).Error == nil && target.DeletionScheduledAt == nil {
+				newOwnerID = target.ID
+			} else {
+				// The chosen user is gone, keep the tasks anonymously instead
+				newOwnerID = placeholder.ID
+			}
+		case TasksDelete:
+		default:
+			newOwnerID = placeholder.ID
+		}
+
+		ownedTasks := tx.Model(&models.Task{}).Select("id").Where("user_id = ?", user.ID)
+		if newOwnerID == 0 {
+			if err := tx.Where("task_id IN (?)", ownedTasks).Delete(&models.Comment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("task_id IN (?)", ownedTasks).Delete(&models.Notification{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("user_id = ?", user.ID).Delete(&models.Task{}).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Model(&models.Task{}).Where("user_id = ?", user.ID).Update("user_id", newOwnerID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Task{}).Where("assignee_id = ?", user.ID).Update("assignee_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RecurringTask{}).Where("assignee_id = ?", user.ID).Update("assignee_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Comment{}).Where("user_id = ?", user.ID).Update("user_id", placeholder.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Notification{}).Where("actor_id = ?", user.ID).Update("actor_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.TaskTransition{}).Where("actor_id = ?", user.ID).Update("actor_id", nil).Error; err != nil {
+			return err
+		}
+
+		// Everything else only belongs to the user
+		for _, record := range []interface{}{
+			&models.Notification{},
+			&models.NotificationPreference{},
+			&models.Session{},
+			&models.UserIdentity{},
+			&models.RecoveryCode{},
+			&models.PersonalAccessToken{},
+			&models.UserToken{},
+			&models.LoginAttempt{},
+			&models.DataExport{},
+			&models.SavedView{},
+			&models.TimeEntry{},
+			&models.RecurringTask{},
+			&models.CalendarFeed{},
+		} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(record).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(&user).Error
+	})
+}
+
+// PurgeDue purges every account whose grace period is over
+func PurgeDue(db *gorm.DB) {
+	var users []models.User
+	if err := db.Where("deletion_scheduled_at <= ?", time.Now()).Find(&users).Error; err != nil {
+		log.Printf("Failed to load accounts to delete: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if err := Purge(db, user); err != nil {
+			log.Printf("Failed to delete account %d: %v", user.ID, err)
+		}
+	}
+}
+
+// StartScheduler purges due accounts and cleans up data exports every
+// interval in the background.
+func StartScheduler(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			PurgeDue(db)
+			cleanUpExports(db)
+			<-ticker.C
+		}
+	}()
+}
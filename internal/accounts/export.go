@@ -0,0 +1,195 @@
+package accounts
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// PersonalData is everything stored about a user
+type PersonalData struct {
+	ExportedAt    time.Time             `json:"exported_at"`
+	Profile       Profile               `json:"profile"`
+	Tasks         []Task                `json:"tasks"`
+	Comments      []Comment             `json:"comments"`
+	Notifications []models.Notification `json:"notifications"`
+	Activity      []Activity            `json:"activity"`
+	TimeEntries   []TimeEntry           `json:"time_entries"`
+}
+
+type Profile struct {
+	ID               uint       `json:"id"`
+	FullName         string     `json:"full_name"`
+	Email            string     `json:"email"`
+	Role             string     `json:"role"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at"`
+	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type Task struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      bool       `json:"status"`
+	CategoryID  uint       `json:"category_id"`
+	Owner       bool       `json:"owner"`
+	AssigneeID  *uint      `json:"assignee_id"`
+	DueDate     *time.Time `json:"due_date"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Activity is something the user did, such as logging in or moving a task
+type Activity struct {
+	Type      string    `json:"type"`
+	Result    string    `json:"result"`
+	Detail    string    `json:"detail"`
+	IP        string    `json:"ip"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type TimeEntry struct {
+	ID        uint       `json:"id"`
+	TaskID    uint       `json:"task_id"`
+	StartedAt time.Time  `json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      string     `json:"note"`
+}
+
+type Comment struct {
+	ID        uint      `json:"id"`
+	TaskID    uint      `json:"task_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Export collects the personal data of a user: their profile, the tasks they
+// own or are assigned to, their comments, their notifications, their
+// login history, the task changes they made and the time they tracked.
+func Export(db *gorm.DB, user models.User) (*PersonalData, error) {
+	data := &PersonalData{
+		ExportedAt: time.Now(),
+		Profile: Profile{
+			ID:               user.ID,
+			FullName:         user.FullName,
+			Email:            user.Email,
+			Role:             user.Role,
+			EmailVerifiedAt:  user.EmailVerifiedAt,
+			TwoFactorEnabled: user.TwoFactorEnabled,
+			CreatedAt:        user.CreatedAt,
+			UpdatedAt:        user.UpdatedAt,
+		},
+		Tasks:         []Task{},
+		Comments:      []Comment{},
+		Notifications: []models.Notification{},
+		Activity:      []Activity{},
+		TimeEntries:   []TimeEntry{},
+	}
+
+	var tasks []models.Task
+	if err := db.Where("user_id = ? OR assignee_id = ?", user.ID, user.ID).Order("id").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		data.Tasks = append(data.Tasks, Task{
+			ID:          task.ID,
+			Title:       task.Title,
+			Description: task.Description,
+			Status:      task.Status,
+			CategoryID:  task.CategoryID,
+			Owner:       task.UserID == user.ID,
+			AssigneeID:  task.AssigneeID,
+			DueDate:     task.DueDate,
+			CreatedAt:   task.CreatedAt,
+			UpdatedAt:   task.UpdatedAt,
+		})
+	}
+
+	var comments []models.Comment
+	if err := db.Where("user_id = ?", user.ID).Order("id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		data.Comments = append(data.Comments, Comment{
+			ID:        comment.ID,
+			TaskID:    comment.TaskID,
+			Body:      comment.Body,
+			CreatedAt: comment.CreatedAt,
+			UpdatedAt: comment.UpdatedAt,
+		})
+	}
+
+	if err := db.Where("user_id = ?", user.ID).Order("id").Find(&data.Notifications).Error; err != nil {
+		return nil, err
+	}
+
+	var attempts []models.LoginAttempt
+	if err := db.Where("user_id = ?", user.ID).Order("id").Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	for _, attempt := range attempts {
+		data.Activity = append(data.Activity, Activity{
+			Type:      "login",
+			Result:    attempt.Result,
+			Detail:    attempt.Reason,
+			IP:        attempt.IP,
+			CreatedAt: attempt.CreatedAt,
+		})
+	}
+
+	var transitions []models.TaskTransition
+	if err := db.Where("actor_id = ?", user.ID).Order("id").Find(&transitions).Error; err != nil {
+		return nil, err
+	}
+	for _, transition := range transitions {
+		data.Activity = append(data.Activity, Activity{
+			Type:      "task",
+			Result:    transition.Kind,
+			Detail:    describeTransition(transition),
+			CreatedAt: transition.CreatedAt,
+		})
+	}
+	sort.SliceStable(data.Activity, func(i, j int) bool {
+		return data.Activity[i].CreatedAt.Before(data.Activity[j].CreatedAt)
+	})
+
+	var entries []models.TimeEntry
+	if err := db.Where("user_id = ?", user.ID).Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data.TimeEntries = append(data.TimeEntries, TimeEntry{
+			ID:        entry.ID,
+			TaskID:    entry.TaskID,
+			StartedAt: entry.StartedAt,
+			EndedAt:   entry.EndedAt,
+			Note:      entry.Note,
+		})
+	}
+
+	return data, nil
+}
+
+func describeTransition(transition models.TaskTransition) string {
+	switch transition.Kind {
+	case models.TransitionCreated:
+		return fmt.Sprintf("created task %d in category %d", transition.TaskID, transition.CategoryID)
+	case models.TransitionMoved:
+		if transition.FromCategoryID != nil {
+			return fmt.Sprintf("moved task %d from category %d to %d", transition.TaskID, *transition.FromCategoryID, transition.CategoryID)
+		}
+		return fmt.Sprintf("moved task %d to category %d", transition.TaskID, transition.CategoryID)
+	case models.TransitionStatus:
+		if transition.Status {
+			return fmt.Sprintf("marked task %d as done", transition.TaskID)
+		}
+		return fmt.Sprintf("marked task %d as not done", transition.TaskID)
+	}
+	return fmt.Sprintf("changed task %d", transition.TaskID)
+}
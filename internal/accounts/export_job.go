@@ -0,0 +1,176 @@
+package accounts
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/notifications"
+	"gorm.io/gorm"
+)
+
+// ExportLinkTTL is how long a finished export can be downloaded
+const ExportLinkTTL = 24 * time.Hour
+
+const exportPurpose = "data_export"
+
+// exportBuildTimeout is how long an export may be building before it is
+// taken for lost, for example because the server stopped while building it
+const exportBuildTimeout = 30 * time.Minute
+
+// RequestExport queues a new export for the user and starts building it in
+// the background. An export that is still being built is returned instead
+// of starting another one.
+func RequestExport(db *gorm.DB, user models.User, includeCSV bool) (*models.DataExport, error) {
+	if err := failLostExports(db.Where("user_id = ?", user.ID)); err != nil {
+		return nil, err
+	}
+
+	var export models.DataExport
+	err := db.Where("user_id = ? AND status IN ?", user.ID, []string{models.DataExportPending, models.DataExportBuilding}).
+		First(&export).Error
+	if err == nil {
+		return &export, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	export = models.DataExport{UserID: user.ID, IncludeCSV: includeCSV, Status: models.DataExportPending}
+	if err := db.Create(&export).Error; err != nil {
+		return nil, err
+	}
+
+	go buildExport(db, export.ID)
+	return &export, nil
+}
+
+// buildExport builds a pending export. Claiming it first makes sure two
+// workers never build the same export.
+func buildExport(db *gorm.DB, exportID uint) {
+	claim := db.Model(&models.DataExport{}).
+		Where("id = ? AND status = ?", exportID, models.DataExportPending).
+		Updates(map[string]interface{}{
+			"status":     models.DataExportBuilding,
+			"started_at": time.Now(),
+		})
+	if claim.Error != nil || claim.RowsAffected == 0 {
+		return
+	}
+
+	var export models.DataExport
+	if err := db.Preload("User").First(&export, exportID).Error; err != nil {
+		log.Printf("Failed to load export %d: %v", exportID, err)
+		return
+	}
+
+	archive, err := buildArchiveFor(db, export)
+	if err != nil {
+		log.Printf("Failed to build export %d: %v", exportID, err)
+		db.Model(&export).Updates(map[string]interface{}{
+			"status": models.DataExportFailed,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(ExportLinkTTL)
+	if err := db.Model(&export).Updates(map[string]interface{}{
+		"status":       models.DataExportReady,
+		"archive":      archive,
+		"completed_at": now,
+		"expires_at":   expiresAt,
+	}).Error; err != nil {
+		log.Printf("Failed to save export %d: %v", exportID, err)
+		return
+	}
+	export.ExpiresAt = &expiresAt
+
+	link, err := DownloadLink(export)
+	if err != nil {
+		log.Printf("Failed to sign export %d link: %v", exportID, err)
+		return
+	}
+	notifications.AccountEmail(notifications.EventDataExport, export.User, link)
+}
+
+func buildArchiveFor(db *gorm.DB, export models.DataExport) ([]byte, error) {
+	data, err := Export(db, export.User)
+	if err != nil {
+		return nil, err
+	}
+	return BuildArchive(data, export.IncludeCSV)
+}
+
+// DownloadLink returns a link to download a ready export without logging in.
+// It works until the export expires.
+func DownloadLink(export models.DataExport) (string, error) {
+	expiresAt := time.Now().Add(ExportLinkTTL)
+	if export.ExpiresAt != nil {
+		expiresAt = *export.ExpiresAt
+	}
+
+	token, err := config.SignToken(&config.UserTokenClaims{
+		UserID:           export.UserID,
+		Purpose:          exportPurpose,
+		RegisteredClaims: config.NewRegisteredClaims(strconv.FormatUint(uint64(export.ID), 10), expiresAt),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/users/exports/%d/download?token=%s", config.AppURL, export.ID, url.QueryEscape(token)), nil
+}
+
+// CheckDownloadToken returns the ready export the token of a download link
+// was signed for
+func CheckDownloadToken(db *gorm.DB, exportID uint, token string) (*models.DataExport, error) {
+	claims := &config.UserTokenClaims{}
+	if err := config.ParseToken(token, claims); err != nil || claims.Purpose != exportPurpose || claims.ID != strconv.FormatUint(uint64(exportID), 10) {
+		return nil, errors.New("Invalid or expired download link")
+	}
+
+	var export models.DataExport
+	if err := db.Where("id = ? AND user_id = ? AND status = ? AND expires_at > ?", exportID, claims.UserID, models.DataExportReady, time.Now()).
+		First(&export).Error; err != nil {
+		return nil, errors.New("Invalid or expired download link")
+	}
+
+	return &export, nil
+}
+
+// failLostExports marks exports that have been building for longer than
+// exportBuildTimeout as failed, so a new one can be requested
+func failLostExports(db *gorm.DB) error {
+	return db.Model(&models.DataExport{}).
+		Where("status = ? AND COALESCE(started_at, created_at) < ?", models.DataExportBuilding, time.Now().Add(-exportBuildTimeout)).
+		Updates(map[string]interface{}{
+			"status": models.DataExportFailed,
+			"error":  "Export took too long to build, please request a new one",
+		}).Error
+}
+
+// cleanUpExports builds exports left pending, for example by a restart,
+// fails the ones lost while building and deletes the archives of expired
+// ones
+func cleanUpExports(db *gorm.DB) {
+	if err := failLostExports(db); err != nil {
+		log.Printf("Failed to mark lost exports as failed: %v", err)
+	}
+
+	var pending []models.DataExport
+	db.Where("status = ? AND created_at < ?", models.DataExportPending, time.Now().Add(-5*time.Minute)).Find(&pending)
+	for _, export := range pending {
+		buildExport(db, export.ID)
+	}
+
+	if err := db.Where("expires_at <= ?", time.Now()).Delete(&models.DataExport{}).Error; err != nil {
+		log.Printf("Failed to delete expired exports: %v", err)
+	}
+}
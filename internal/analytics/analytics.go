@@ -0,0 +1,387 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// Scope is the set of tasks the analytics are computed on
+type Scope struct {
+	UserID uint
+	// AllTasks uses every task instead of those the user owns or is
+	// assigned to
+	AllTasks bool
+}
+
+// state is where a task was from a point in time on
+type state struct {
+	at         time.Time
+	categoryID uint
+	status     bool
+}
+
+// timeline is the history of one task. Work on a task starts when it is
+// first moved to another category and ends when it is marked done.
+type timeline struct {
+	task      models.Task
+	states    []state
+	startedAt *time.Time
+	doneAt    *time.Time
+	// Tasks from before the history was recorded only have their current
+	// state, so their cycle and lead times are unknown
+	recorded bool
+}
+
+func (t timeline) stateAt(at time.Time) (state, bool) {
+	var current state
+	found := false
+	for _, s := range t.states {
+		if s.at.After(at) {
+			break
+		}
+		current, found = s, true
+	}
+	return current, found
+}
+
+func loadTimelines(db *gorm.DB, scope Scope) ([]timeline, error) {
+	query := db.Model(&models.Task{})
+	if !scope.AllTasks {
+		query = query.Where("user_id = ? OR assignee_id = ?", scope.UserID, scope.UserID)
+	}
+	return loadTaskTimelines(db, query)
+}
+
+// loadTaskTimelines loads the history of the tasks found by query
+func loadTaskTimelines(db *gorm.DB, query *gorm.DB) ([]timeline, error) {
+	var tasks []models.Task
+	if err := query.Order("id").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	var transitions []models.TaskTransition
+	if len(ids) > 0 {
+		if err := db.Where("task_id IN ?", ids).Order("created_at, id").Find(&transitions).Error; err != nil {
+			return nil, err
+		}
+	}
+	byTask := map[uint][]models.TaskTransition{}
+	for _, transition := range transitions {
+		byTask[transition.TaskID] = append(byTask[transition.TaskID], transition)
+	}
+
+	timelines := make([]timeline, 0, len(tasks))
+	for _, task := range tasks {
+		timelines = append(timelines, buildTimeline(task, byTask[task.ID]))
+	}
+	return timelines, nil
+}
+
+func buildTimeline(task models.Task, transitions []models.TaskTransition) timeline {
+	t := timeline{task: task, recorded: len(transitions) > 0}
+
+	initial := state{at: task.CreatedAt, categoryID: task.CategoryID, status: task.Status}
+	if len(transitions) > 0 {
+		first := transitions[0]
+		if first.Kind == models.TransitionCreated {
+			initial.categoryID, initial.status = first.CategoryID, first.Status
+			transitions = transitions[1:]
+		} else if first.FromCategoryID != nil && first.FromStatus != nil {
+			initial.categoryID, initial.status = *first.FromCategoryID, *first.FromStatus
+		}
+	}
+	t.states = append(t.states, initial)
+
+	for _, transition := range transitions {
+		at := transition.CreatedAt
+		if transition.Kind == models.TransitionMoved && t.startedAt == nil {
+			t.startedAt = &at
+		}
+		t.states = append(t.states, state{at: at, categoryID: transition.CategoryID, status: transition.Status})
+	}
+
+	// Done is the last time the task was marked done, if it still is. Tasks
+	// imported as done were never seen being completed.
+	if task.Status {
+		for i := len(t.states) - 1; i > 0; i-- {
+			if !t.states[i-1].status {
+				at := t.states[i].at
+				t.doneAt = &at
+				break
+			}
+		}
+	}
+
+	return t
+}
+
+// Stats summarizes durations in hours
+type Stats struct {
+	Count   int     `json:"count"`
+	Average float64 `json:"average_hours"`
+	P50     float64 `json:"p50_hours"`
+	P85     float64 `json:"p85_hours"`
+	P95     float64 `json:"p95_hours"`
+}
+
+func summarize(durations []time.Duration) Stats {
+	stats := Stats{Count: len(durations)}
+	if len(durations) == 0 {
+		return stats
+	}
+
+	hours := make([]float64, len(durations))
+	total := 0.0
+	for i, d := range durations {
+		hours[i] = d.Hours()
+		total += hours[i]
+	}
+	sort.Float64s(hours)
+
+	stats.Average = round(total / float64(len(hours)))
+	stats.P50 = round(percentile(hours, 50))
+	stats.P85 = round(percentile(hours, 85))
+	stats.P95 = round(percentile(hours, 95))
+	return stats
+}
+
+// percentile uses the nearest rank of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func round(hours float64) float64 {
+	return math.Round(hours*10) / 10
+}
+
+type CategoryCount struct {
+	CategoryID uint   `json:"category_id"`
+	Type       string `json:"type"`
+	Count      int    `json:"count"`
+}
+
+type FlowDay struct {
+	Date       string          `json:"date"`
+	Categories []CategoryCount `json:"categories"`
+	Done       int             `json:"done"`
+}
+
+// CumulativeFlow counts the tasks in each category at the end of every day
+// from from to to, and how many of them were done
+func CumulativeFlow(db *gorm.DB, scope Scope, from, to time.Time) ([]FlowDay, error) {
+	timelines, err := loadTimelines(db, scope)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := loadCategories(db)
+	if err != nil {
+		return nil, err
+	}
+
+	days := []FlowDay{}
+	for day := startOfDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		endOfDay := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+
+		counts := map[uint]int{}
+		done := 0
+		for _, t := range timelines {
+			s, ok := t.stateAt(endOfDay)
+			if !ok {
+				continue
+			}
+			counts[s.categoryID]++
+			if s.status {
+				done++
+			}
+		}
+
+		flow := FlowDay{Date: day.Format("2006-01-02"), Categories: []CategoryCount{}, Done: done}
+		for _, category := range categories {
+			flow.Categories = append(flow.Categories, CategoryCount{CategoryID: category.ID, Type: category.Type, Count: counts[category.ID]})
+		}
+		days = append(days, flow)
+	}
+
+	return days, nil
+}
+
+type CycleTimeReport struct {
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Completed int       `json:"completed"`
+	// LeadTime runs from the creation of a task until it is done
+	LeadTime Stats `json:"lead_time"`
+	// CycleTime runs from the first move of a task until it is done. Tasks
+	// done without being moved count from their creation.
+	CycleTime Stats `json:"cycle_time"`
+}
+
+// CycleTime reports the lead and cycle times of the tasks done between from
+// and to
+func CycleTime(db *gorm.DB, scope Scope, from, to time.Time) (*CycleTimeReport, error) {
+	timelines, err := loadTimelines(db, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	var lead, cycle []time.Duration
+	for _, t := range timelines {
+		if t.doneAt == nil || t.doneAt.Before(from) || t.doneAt.After(to) {
+			continue
+		}
+		created := t.task.CreatedAt
+		started := created
+		if t.startedAt != nil && !t.startedAt.After(*t.doneAt) {
+			started = *t.startedAt
+		}
+		lead = append(lead, t.doneAt.Sub(created))
+		cycle = append(cycle, t.doneAt.Sub(started))
+	}
+
+	return &CycleTimeReport{
+		From:      from,
+		To:        to,
+		Completed: len(lead),
+		LeadTime:  summarize(lead),
+		CycleTime: summarize(cycle),
+	}, nil
+}
+
+type ThroughputWeek struct {
+	WeekStart string `json:"week_start"`
+	Completed int    `json:"completed"`
+}
+
+// Throughput counts the tasks done in each of the last weeks, weeks starting
+// on Monday and the current week last
+func Throughput(db *gorm.DB, scope Scope, weeks int, now time.Time) ([]ThroughputWeek, error) {
+	timelines, err := loadTimelines(db, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	today := startOfDay(now)
+	currentWeek := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+	firstWeek := currentWeek.AddDate(0, 0, -7*(weeks-1))
+
+	result := make([]ThroughputWeek, weeks)
+	for i := range result {
+		result[i].WeekStart = firstWeek.AddDate(0, 0, 7*i).Format("2006-01-02")
+	}
+	for _, t := range timelines {
+		if t.doneAt == nil || t.doneAt.Before(firstWeek) {
+			continue
+		}
+		week := int(t.doneAt.Sub(firstWeek).Hours() / (24 * 7))
+		if week < weeks {
+			result[week].Completed++
+		}
+	}
+
+	return result, nil
+}
+
+type AgingTask struct {
+	TaskID     uint      `json:"task_id"`
+	Title      string    `json:"title"`
+	CategoryID uint      `json:"category_id"`
+	Started    bool      `json:"started"`
+	Since      time.Time `json:"since"`
+	AgeDays    float64   `json:"age_days"`
+}
+
+type AgingCategory struct {
+	CategoryID     uint    `json:"category_id"`
+	Type           string  `json:"type"`
+	Count          int     `json:"count"`
+	AverageAgeDays float64 `json:"average_age_days"`
+	OldestAgeDays  float64 `json:"oldest_age_days"`
+}
+
+type AgingReport struct {
+	Tasks      []AgingTask     `json:"tasks"`
+	Categories []AgingCategory `json:"categories"`
+}
+
+// AgingWIP lists the open tasks, oldest first, with how long they have been
+// in progress: since their first move, or since their creation when they
+// were never moved
+func AgingWIP(db *gorm.DB, scope Scope, now time.Time) (*AgingReport, error) {
+	timelines, err := loadTimelines(db, scope)
+	if err != nil {
+		return nil, err
+	}
+	categories, err := loadCategories(db)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &AgingReport{Tasks: []AgingTask{}, Categories: []AgingCategory{}}
+	totals := map[uint]float64{}
+	oldest := map[uint]float64{}
+	counts := map[uint]int{}
+	for _, t := range timelines {
+		if t.task.Status {
+			continue
+		}
+		since := t.task.CreatedAt
+		if t.startedAt != nil {
+			since = *t.startedAt
+		}
+		age := math.Round(now.Sub(since).Hours()/24*10) / 10
+
+		report.Tasks = append(report.Tasks, AgingTask{
+			TaskID:     t.task.ID,
+			Title:      t.task.Title,
+			CategoryID: t.task.CategoryID,
+			Started:    t.startedAt != nil,
+			Since:      since,
+			AgeDays:    age,
+		})
+		counts[t.task.CategoryID]++
+		totals[t.task.CategoryID] += age
+		if age > oldest[t.task.CategoryID] {
+			oldest[t.task.CategoryID] = age
+		}
+	}
+	sort.SliceStable(report.Tasks, func(i, j int) bool { return report.Tasks[i].AgeDays > report.Tasks[j].AgeDays })
+
+	for _, category := range categories {
+		count := counts[category.ID]
+		if count == 0 {
+			continue
+		}
+		report.Categories = append(report.Categories, AgingCategory{
+			CategoryID:     category.ID,
+			Type:           category.Type,
+			Count:          count,
+			AverageAgeDays: math.Round(totals[category.ID]/float64(count)*10) / 10,
+			OldestAgeDays:  oldest[category.ID],
+		})
+	}
+
+	return report, nil
+}
+
+func loadCategories(db *gorm.DB) ([]models.Category, error) {
+	var categories []models.Category
+	err := db.Order("id").Find(&categories).Error
+	return categories, err
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
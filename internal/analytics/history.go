@@ -0,0 +1,66 @@
+package analytics
+
+import (
+	"log"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// A failure to record the history is logged and returned. Handlers go on
+// without it, imports and bulk changes roll back their transaction.
+
+// RecordCreated records the creation of a task
+func RecordCreated(db *gorm.DB, task models.Task, actorID uint) error {
+	return record(db, models.TaskTransition{
+		TaskID:     task.ID,
+		ActorID:    &actorID,
+		Kind:       models.TransitionCreated,
+		CategoryID: task.CategoryID,
+		Status:     task.Status,
+	})
+}
+
+// RecordMove records a task being moved from its current category to
+// categoryID. Nothing is recorded when the category stays the same.
+func RecordMove(db *gorm.DB, task models.Task, categoryID uint, actorID uint) error {
+	if task.CategoryID == categoryID {
+		return nil
+	}
+	fromCategoryID, fromStatus := task.CategoryID, task.Status
+	return record(db, models.TaskTransition{
+		TaskID:         task.ID,
+		ActorID:        &actorID,
+		Kind:           models.TransitionMoved,
+		FromCategoryID: &fromCategoryID,
+		CategoryID:     categoryID,
+		FromStatus:     &fromStatus,
+		Status:         task.Status,
+	})
+}
+
+// RecordStatus records the status of a task changing from its current one
+// to status. Nothing is recorded when the status stays the same.
+func RecordStatus(db *gorm.DB, task models.Task, status bool, actorID uint) error {
+	if task.Status == status {
+		return nil
+	}
+	fromCategoryID, fromStatus := task.CategoryID, task.Status
+	return record(db, models.TaskTransition{
+		TaskID:         task.ID,
+		ActorID:        &actorID,
+		Kind:           models.TransitionStatus,
+		FromCategoryID: &fromCategoryID,
+		CategoryID:     task.CategoryID,
+		FromStatus:     &fromStatus,
+		Status:         status,
+	})
+}
+
+func record(db *gorm.DB, transition models.TaskTransition) error {
+	err := db.Create(&transition).Error
+	if err != nil {
+		log.Printf("Failed to record the history of task %d: %v", transition.TaskID, err)
+	}
+	return err
+}
@@ -0,0 +1,123 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// Sprints use the tasks currently planned in them. Tasks without an
+// estimate count as zero.
+
+type BurndownDay struct {
+	Date string `json:"date"`
+	// Remaining is the estimate left at the end of the day, missing for
+	// days still to come
+	Remaining *float64 `json:"remaining"`
+	Ideal     float64  `json:"ideal"`
+}
+
+type Burndown struct {
+	SprintID uint          `json:"sprint_id"`
+	Total    float64       `json:"total"`
+	Days     []BurndownDay `json:"days"`
+}
+
+// SprintBurndown returns the estimate of the open tasks of a sprint at the
+// end of each of its days, next to a straight line from the total to zero
+func SprintBurndown(db *gorm.DB, sprint models.Sprint, now time.Time) (*Burndown, error) {
+	timelines, err := loadTaskTimelines(db, db.Model(&models.Task{}).Where("sprint_id = ?", sprint.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	burndown := &Burndown{SprintID: sprint.ID, Days: []BurndownDay{}}
+	for _, t := range timelines {
+		burndown.Total += estimate(t.task)
+	}
+
+	var days []time.Time
+	for day := startOfDay(sprint.StartDate); !day.After(sprint.EndDate); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	for i, day := range days {
+		ideal := burndown.Total
+		if len(days) > 1 {
+			ideal = burndown.Total * (1 - float64(i)/float64(len(days)-1))
+		}
+		point := BurndownDay{Date: day.Format("2006-01-02"), Ideal: roundPoints(ideal)}
+
+		if !day.After(now) {
+			endOfDay := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+			remaining := 0.0
+			for _, t := range timelines {
+				if s, ok := t.stateAt(endOfDay); ok && !s.status {
+					remaining += estimate(t.task)
+				}
+			}
+			remaining = roundPoints(remaining)
+			point.Remaining = &remaining
+		}
+		burndown.Days = append(burndown.Days, point)
+	}
+
+	return burndown, nil
+}
+
+type SprintVelocity struct {
+	SprintID  uint      `json:"sprint_id"`
+	Name      string    `json:"name"`
+	StartDate time.Time `json:"start_date"`
+	EndDate   time.Time `json:"end_date"`
+	Committed float64   `json:"committed"`
+	Completed float64   `json:"completed"`
+}
+
+type Velocity struct {
+	Sprints []SprintVelocity `json:"sprints"`
+	// Average is the mean completed estimate of the sprints
+	Average float64 `json:"average"`
+}
+
+// SprintsVelocity compares the estimate planned in each sprint with the
+// estimate done by its end
+func SprintsVelocity(db *gorm.DB, sprints []models.Sprint) (*Velocity, error) {
+	velocity := &Velocity{Sprints: []SprintVelocity{}}
+	total := 0.0
+	for _, sprint := range sprints {
+		timelines, err := loadTaskTimelines(db, db.Model(&models.Task{}).Where("sprint_id = ?", sprint.ID))
+		if err != nil {
+			return nil, err
+		}
+
+		result := SprintVelocity{SprintID: sprint.ID, Name: sprint.Name, StartDate: sprint.StartDate, EndDate: sprint.EndDate}
+		for _, t := range timelines {
+			result.Committed += estimate(t.task)
+			if s, ok := t.stateAt(sprint.EndDate); ok && s.status {
+				result.Completed += estimate(t.task)
+			}
+		}
+		result.Committed = roundPoints(result.Committed)
+		result.Completed = roundPoints(result.Completed)
+		total += result.Completed
+		velocity.Sprints = append(velocity.Sprints, result)
+	}
+	if len(sprints) > 0 {
+		velocity.Average = roundPoints(total / float64(len(sprints)))
+	}
+
+	return velocity, nil
+}
+
+func estimate(task models.Task) float64 {
+	if task.Estimate == nil {
+		return 0
+	}
+	return *task.Estimate
+}
+
+func roundPoints(points float64) float64 {
+	return math.Round(points*100) / 100
+}
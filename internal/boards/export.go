@@ -0,0 +1,149 @@
+package boards
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/csvsafe"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// Board is the full export of the categories and their tasks. It is also
+// the JSON format accepted by Import.
+type Board struct {
+	ExportedAt time.Time  `json:"exported_at"`
+	Categories []Category `json:"categories"`
+}
+
+type Category struct {
+	Type  string `json:"type"`
+	Tasks []Task `json:"tasks"`
+}
+
+type Task struct {
+	Title       string          `json:"title"`
+	Description string          `json:"description"`
+	Status      bool            `json:"status"`
+	Owner       string          `json:"owner,omitempty"`
+	Assignee    string          `json:"assignee,omitempty"`
+	DueDate     *time.Time      `json:"due_date"`
+	Labels      []Label         `json:"labels,omitempty"`
+	Checklist   []ChecklistItem `json:"checklist,omitempty"`
+	CreatedAt   *time.Time      `json:"created_at,omitempty"`
+}
+
+type Label struct {
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+type ChecklistItem struct {
+	Checklist string `json:"checklist,omitempty"`
+	Text      string `json:"text"`
+	Done      bool   `json:"done"`
+}
+
+// CSVColumns are the columns of a flat CSV export, one row per task. Labels
+// and checklists are only kept by the JSON format.
+var CSVColumns = []string{"category", "title", "description", "status", "owner", "assignee", "due_date", "created_at"}
+
+// Export loads every category with its tasks. Only the tasks the user owns
+// or is assigned to are included unless allTasks is set.
+func Export(db *gorm.DB, userID uint, allTasks bool) (*Board, error) {
+	var categories []models.Category
+	if err := db.Order("id").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	query := db.Preload("User").Preload("Assignee").Preload("Labels").Order("id")
+	if !allTasks {
+		query = query.Where("user_id = ? OR assignee_id = ?", userID, userID)
+	}
+	var tasks []models.Task
+	if err := query.Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	var taskIDs []uint
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.ID)
+	}
+	var items []models.ChecklistItem
+	if err := db.Where("task_id IN ?", taskIDs).Order("task_id, position, id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	checklists := map[uint][]ChecklistItem{}
+	for _, item := range items {
+		checklists[item.TaskID] = append(checklists[item.TaskID], ChecklistItem{Checklist: item.Checklist, Text: item.Text, Done: item.Done})
+	}
+
+	board := &Board{ExportedAt: time.Now(), Categories: []Category{}}
+	index := map[uint]int{}
+	for _, category := range categories {
+		index[category.ID] = len(board.Categories)
+		board.Categories = append(board.Categories, Category{Type: category.Type, Tasks: []Task{}})
+	}
+
+	for _, task := range tasks {
+		i, ok := index[task.CategoryID]
+		if !ok {
+			continue
+		}
+		exported := Task{
+			Title:       task.Title,
+			Description: task.Description,
+			Status:      task.Status,
+			Owner:       task.User.Email,
+			DueDate:     task.DueDate,
+			Checklist:   checklists[task.ID],
+			CreatedAt:   &task.CreatedAt,
+		}
+		for _, label := range task.Labels {
+			exported.Labels = append(exported.Labels, Label{Name: label.Name, Color: label.Color})
+		}
+		if task.Assignee != nil {
+			exported.Assignee = task.Assignee.Email
+		}
+		board.Categories[i].Tasks = append(board.Categories[i].Tasks, exported)
+	}
+
+	return board, nil
+}
+
+// WriteCSV writes the board as a flat CSV file with the CSVColumns, its
+// cells escaped for spreadsheets
+func WriteCSV(w io.Writer, board *Board) error {
+	writer := csvsafe.NewWriter(w)
+	if err := writer.Write(CSVColumns); err != nil {
+		return err
+	}
+
+	for _, category := range board.Categories {
+		for _, task := range category.Tasks {
+			if err := writer.Write([]string{
+				category.Type,
+				task.Title,
+				task.Description,
+				strconv.FormatBool(task.Status),
+				task.Owner,
+				task.Assignee,
+				formatTime(task.DueDate),
+				formatTime(task.CreatedAt),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,325 @@
+package boards
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/analytics"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/csvsafe"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// Row is one task to import. Line is where it was found in the file, so
+// errors can point at it.
+type Row struct {
+	Line        int
+	Category    string
+	Title       string
+	Description string
+	Status      bool
+	Assignee    string
+	DueDate     *time.Time
+	Labels      []Label
+	Checklist   []ChecklistItem
+}
+
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type Result struct {
+	DryRun            bool       `json:"dry_run"`
+	TasksCreated      int        `json:"tasks_created"`
+	CategoriesCreated []string   `json:"categories_created"`
+	LabelsCreated     []string   `json:"labels_created"`
+	Errors            []RowError `json:"errors"`
+	// Unsupported lists what the file had that could not be imported
+	Unsupported []Unsupported `json:"unsupported,omitempty"`
+}
+
+// Unsupported is a kind of data that was left out of an import
+type Unsupported struct {
+	Field   string `json:"field"`
+	Count   int    `json:"count"`
+	Message string `json:"message"`
+}
+
+type Options struct {
+	// OwnerID owns every imported task
+	OwnerID uint
+	// CreateCategories creates categories that do not exist yet instead of
+	// rejecting their rows
+	CreateCategories bool
+	// CreateLabels creates labels that do not exist yet instead of
+	// rejecting their rows
+	CreateLabels bool
+	// DryRun checks the rows and reports what would be created without
+	// saving anything
+	DryRun bool
+}
+
+// errRollback undoes the import transaction after a dry run or row errors
+var errRollback = errors.New("rollback")
+
+// ParseCSV reads a CSV file whose first line names the columns. mapping
+// gives the header used for a column when it is not the column name itself,
+// for example {"category": "List"}. Columns that are not needed are ignored.
+func ParseCSV(r io.Reader, mapping map[string]string) ([]Row, []RowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read the CSV header: %v", err)
+	}
+
+	headerIndex := map[string]int{}
+	for i, name := range header {
+		headerIndex[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	columns := map[string]int{}
+	for _, column := range CSVColumns {
+		name := column
+		if mapped, ok := mapping[column]; ok {
+			name = mapped
+		}
+		if i, ok := headerIndex[strings.ToLower(name)]; ok {
+			columns[column] = i
+		}
+	}
+	for _, required := range []string{"category", "title"} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("The CSV file has no %s column", required)
+		}
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		field := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return csvsafe.Unescape(strings.TrimSpace(record[i]))
+			}
+			return ""
+		}
+
+		row := Row{
+			Line:        line,
+			Category:    field("category"),
+			Title:       field("title"),
+			Description: field("description"),
+			Assignee:    field("assignee"),
+		}
+		if row.Status, err = parseStatus(field("status")); err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Error: err.Error()})
+			continue
+		}
+		if row.DueDate, err = parseDate(field("due_date")); err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+// ParseJSON reads a file in the Board format written by Export. Line is
+// the position of the task in the file, counting from 1.
+func ParseJSON(r io.Reader) ([]Row, error) {
+	var board Board
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, err
+	}
+
+	var rows []Row
+	for _, category := range board.Categories {
+		for _, task := range category.Tasks {
+			rows = append(rows, Row{
+				Line:        len(rows) + 1,
+				Category:    category.Type,
+				Title:       task.Title,
+				Description: task.Description,
+				Status:      task.Status,
+				Assignee:    task.Assignee,
+				DueDate:     task.DueDate,
+				Labels:      task.Labels,
+				Checklist:   task.Checklist,
+			})
+		}
+	}
+	return rows, nil
+}
+
+func parseStatus(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "", "false", "0", "no", "todo", "open":
+		return false, nil
+	case "true", "1", "yes", "done", "closed":
+		return true, nil
+	}
+	return false, fmt.Errorf("Invalid status %q", value)
+}
+
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("Invalid due date %q", value)
+}
+
+// Import creates the tasks of the rows in one transaction. If any row is
+// invalid nothing is created and every problem is reported.
+func Import(db *gorm.DB, rows []Row, opts Options) (*Result, error) {
+	result := &Result{DryRun: opts.DryRun, CategoriesCreated: []string{}, LabelsCreated: []string{}, Errors: []RowError{}}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var categories []models.Category
+		if err := tx.Find(&categories).Error; err != nil {
+			return err
+		}
+		categoryIDs := map[string]uint{}
+		for _, category := range categories {
+			categoryIDs[strings.ToLower(category.Type)] = category.ID
+		}
+		assignees := map[string]*uint{}
+		labels := map[Label]models.Label{}
+
+		for _, row := range rows {
+			if row.Title == "" {
+				result.Errors = append(result.Errors, RowError{Line: row.Line, Error: "Title is required"})
+				continue
+			}
+			if row.Category == "" {
+				result.Errors = append(result.Errors, RowError{Line: row.Line, Error: "Category is required"})
+				continue
+			}
+
+			categoryID, ok := categoryIDs[strings.ToLower(row.Category)]
+			if !ok {
+				if !opts.CreateCategories {
+					result.Errors = append(result.Errors, RowError{Line: row.Line, Error: fmt.Sprintf("Category %q does not exist", row.Category)})
+					continue
+				}
+				category := models.Category{Type: row.Category}
+				if err := tx.Create(&category).Error; err != nil {
+					return err
+				}
+				categoryID = category.ID
+				categoryIDs[strings.ToLower(row.Category)] = categoryID
+				result.CategoriesCreated = append(result.CategoriesCreated, row.Category)
+			}
+
+			task := models.Task{
+				Title:       row.Title,
+				Description: row.Description,
+				Status:      row.Status,
+				UserID:      opts.OwnerID,
+				CategoryID:  categoryID,
+				DueDate:     row.DueDate,
+			}
+
+			if row.Assignee != "" {
+				assigneeID, ok := assignees[row.Assignee]
+				if !ok {
+					var assignee models.User
+					if err := tx.Where("LOWER(email) = ?", config.NormalizeEmail(row.Assignee)).First(&assignee).Error; err == nil && config.AccountBlocked(assignee) == nil {
+						assigneeID = &assignee.ID
+					}
+					assignees[row.Assignee] = assigneeID
+				}
+				if assigneeID == nil {
+					result.Errors = append(result.Errors, RowError{Line: row.Line, Error: fmt.Sprintf("Assignee %q not found", row.Assignee)})
+					continue
+				}
+				task.AssigneeID = assigneeID
+			}
+
+			labelErr := ""
+			for _, spec := range row.Labels {
+				label, ok := labels[spec]
+				if !ok {
+					label = models.Label{Name: spec.Name, Color: spec.Color}
+					query := tx.Where("name = ? AND color = ?", spec.Name, spec.Color).Limit(1).Find(&label)
+					if query.Error != nil {
+						return query.Error
+					}
+					if query.RowsAffected == 0 {
+						if !opts.CreateLabels {
+							labelErr = fmt.Sprintf("Label %q does not exist", spec.Name)
+							break
+						}
+						if err := tx.Create(&label).Error; err != nil {
+							return err
+						}
+						result.LabelsCreated = append(result.LabelsCreated, spec.Name)
+					}
+					labels[spec] = label
+				}
+				task.Labels = append(task.Labels, label)
+			}
+			if labelErr != "" {
+				result.Errors = append(result.Errors, RowError{Line: row.Line, Error: labelErr})
+				continue
+			}
+
+			if err := tx.Create(&task).Error; err != nil {
+				return err
+			}
+			if err := analytics.RecordCreated(tx, task, opts.OwnerID); err != nil {
+				return err
+			}
+			for position, spec := range row.Checklist {
+				item := models.ChecklistItem{
+					TaskID:    task.ID,
+					Checklist: spec.Checklist,
+					Text:      spec.Text,
+					Done:      spec.Done,
+					Position:  position,
+				}
+				if err := tx.Create(&item).Error; err != nil {
+					return err
+				}
+			}
+			result.TasksCreated++
+		}
+
+		if opts.DryRun || len(result.Errors) > 0 {
+			return errRollback
+		}
+		return nil
+	})
+	if err != nil && err != errRollback {
+		return nil, err
+	}
+
+	// Nothing was saved when there are errors
+	if len(result.Errors) > 0 {
+		result.TasksCreated = 0
+		result.CategoriesCreated = []string{}
+		result.LabelsCreated = []string{}
+	}
+	return result, nil
+}
@@ -0,0 +1,178 @@
+package boards
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// TrelloBoard is the part of a Trello board JSON export that is imported.
+// Other fields are only counted to report what was left out.
+type TrelloBoard struct {
+	Name       string            `json:"name"`
+	Lists      []trelloList      `json:"lists"`
+	Cards      []trelloCard      `json:"cards"`
+	Labels     []trelloLabel     `json:"labels"`
+	Checklists []trelloChecklist `json:"checklists"`
+	Actions    []struct {
+		Type string `json:"type"`
+	} `json:"actions"`
+	CustomFields []json.RawMessage `json:"customFields"`
+}
+
+type trelloList struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Closed bool    `json:"closed"`
+	Pos    float64 `json:"pos"`
+}
+
+type trelloCard struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Desc        string     `json:"desc"`
+	IDList      string     `json:"idList"`
+	Closed      bool       `json:"closed"`
+	Due         *time.Time `json:"due"`
+	DueComplete bool       `json:"dueComplete"`
+	Start       *time.Time `json:"start"`
+	IDLabels    []string   `json:"idLabels"`
+	IDMembers   []string   `json:"idMembers"`
+	Pos         float64    `json:"pos"`
+	Badges      struct {
+		Attachments int `json:"attachments"`
+	} `json:"badges"`
+	CustomFieldItems []json.RawMessage `json:"customFieldItems"`
+}
+
+type trelloLabel struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type trelloChecklist struct {
+	ID         string  `json:"id"`
+	IDCard     string  `json:"idCard"`
+	Name       string  `json:"name"`
+	Pos        float64 `json:"pos"`
+	CheckItems []struct {
+		Name  string  `json:"name"`
+		State string  `json:"state"`
+		Pos   float64 `json:"pos"`
+	} `json:"checkItems"`
+}
+
+// ParseTrello reads a Trello board JSON export
+func ParseTrello(r io.Reader) (*TrelloBoard, error) {
+	var board TrelloBoard
+	if err := json.NewDecoder(r).Decode(&board); err != nil {
+		return nil, err
+	}
+	if len(board.Lists) == 0 && len(board.Cards) == 0 {
+		return nil, fmt.Errorf("The file is not a Trello board export")
+	}
+	return &board, nil
+}
+
+// Rows turns the cards into rows for Import. Lists become categories, and
+// cards that are closed, are in a closed list or have their due date marked
+// complete are imported as done. Line is the position of the card in the
+// export, counting from 1.
+func (b *TrelloBoard) Rows() ([]Row, []Unsupported) {
+	lists := map[string]trelloList{}
+	for _, list := range b.Lists {
+		lists[list.ID] = list
+	}
+
+	labels := map[string]Label{}
+	for _, label := range b.Labels {
+		name := label.Name
+		if name == "" {
+			name = label.Color
+		}
+		if name == "" {
+			continue
+		}
+		labels[label.ID] = Label{Name: name, Color: label.Color}
+	}
+
+	checklists := map[string][]trelloChecklist{}
+	for _, checklist := range b.Checklists {
+		checklists[checklist.IDCard] = append(checklists[checklist.IDCard], checklist)
+	}
+
+	var members, attachments, customFields, startDates int
+	var rows []Row
+	for i, card := range b.Cards {
+		list := lists[card.IDList]
+		row := Row{
+			Line:        i + 1,
+			Category:    list.Name,
+			Title:       card.Name,
+			Description: card.Desc,
+			Status:      card.Closed || list.Closed || card.DueComplete,
+			DueDate:     card.Due,
+		}
+
+		for _, id := range card.IDLabels {
+			if label, ok := labels[id]; ok {
+				row.Labels = append(row.Labels, label)
+			}
+		}
+
+		cardChecklists := checklists[card.ID]
+		sort.SliceStable(cardChecklists, func(i, j int) bool { return cardChecklists[i].Pos < cardChecklists[j].Pos })
+		for _, checklist := range cardChecklists {
+			items := checklist.CheckItems
+			sort.SliceStable(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+			for _, item := range items {
+				row.Checklist = append(row.Checklist, ChecklistItem{
+					Checklist: checklist.Name,
+					Text:      item.Name,
+					Done:      item.State == "complete",
+				})
+			}
+		}
+
+		if len(card.IDMembers) > 0 {
+			members++
+		}
+		if card.Badges.Attachments > 0 {
+			attachments++
+		}
+		if len(card.CustomFieldItems) > 0 {
+			customFields++
+		}
+		if card.Start != nil {
+			startDates++
+		}
+
+		rows = append(rows, row)
+	}
+
+	var comments int
+	for _, action := range b.Actions {
+		if action.Type == "commentCard" {
+			comments++
+		}
+	}
+
+	var unsupported []Unsupported
+	for _, u := range []Unsupported{
+		{Field: "idMembers", Count: members, Message: "Card members are not imported, assign the tasks after the import"},
+		{Field: "attachments", Count: attachments, Message: "Attachments are not imported"},
+		{Field: "customFieldItems", Count: customFields, Message: "Custom field values are not imported"},
+		{Field: "start", Count: startDates, Message: "Start dates are not imported"},
+		{Field: "actions.commentCard", Count: comments, Message: "Comments are not imported"},
+		{Field: "customFields", Count: len(b.CustomFields), Message: "Custom field definitions are not imported"},
+	} {
+		if u.Count > 0 {
+			unsupported = append(unsupported, u)
+		}
+	}
+
+	return rows, unsupported
+}
@@ -0,0 +1,122 @@
+// Package calendar writes the due tasks of a user as an iCalendar feed
+// (RFC 5545) of to-dos that calendar apps can subscribe to.
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+)
+
+const timestampFormat = "20060102T150405Z"
+
+// RefreshInterval is how often calendar apps are asked to fetch the feed
+const RefreshInterval = 15 * time.Minute
+
+// Event is a due task with the name of its category and, for a done task,
+// when it was done
+type Event struct {
+	Task        models.Task
+	Category    string
+	CompletedAt *time.Time
+}
+
+// WriteFeed writes the events as a calendar. Each task is a to-do due at its
+// due date; done tasks are completed, at their UpdatedAt when CompletedAt is
+// not known.
+func WriteFeed(w io.Writer, name, appURL string, events []Event, now time.Time) error {
+	host := "localhost"
+	if u, err := url.Parse(appURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	refresh := fmt.Sprintf("PT%dM", int(RefreshInterval.Minutes()))
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Kanban Board//Due Tasks//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:" + escape(name),
+		"REFRESH-INTERVAL;VALUE=DURATION:" + refresh,
+		"X-PUBLISHED-TTL:" + refresh,
+	}
+
+	for _, event := range events {
+		task := event.Task
+		if task.DueDate == nil {
+			continue
+		}
+
+		lines = append(lines,
+			"BEGIN:VTODO",
+			fmt.Sprintf("UID:task-%d@%s", task.ID, host),
+			"DTSTAMP:"+now.UTC().Format(timestampFormat),
+			"DUE:"+task.DueDate.UTC().Format(timestampFormat),
+			"LAST-MODIFIED:"+task.UpdatedAt.UTC().Format(timestampFormat),
+			"SUMMARY:"+escape(task.Title),
+		)
+		if task.Description != "" {
+			lines = append(lines, "DESCRIPTION:"+escape(task.Description))
+		}
+		if task.Status {
+			completedAt := task.UpdatedAt
+			if event.CompletedAt != nil {
+				completedAt = *event.CompletedAt
+			}
+			lines = append(lines,
+				"STATUS:COMPLETED",
+				"COMPLETED:"+completedAt.UTC().Format(timestampFormat),
+				"PERCENT-COMPLETE:100",
+			)
+		} else {
+			lines = append(lines, "STATUS:NEEDS-ACTION")
+		}
+		if event.Category != "" {
+			lines = append(lines, "CATEGORIES:"+escape(event.Category))
+		}
+		lines = append(lines, "END:VTODO")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, fold(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escape escapes a TEXT value
+func escape(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(text)
+}
+
+// fold splits lines longer than 75 octets, without cutting a UTF-8
+// character in two
+func fold(line string) string {
+	const limit = 75
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// CalendarTokenPrefix starts every calendar feed token
+const CalendarTokenPrefix = "kbc_"
+
+// GenerateCalendarToken returns a new calendar feed token and the hash to
+// store for it
+func GenerateCalendarToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := CalendarTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashCalendarToken(token), nil
+}
+
+func HashCalendarToken(token string) string {
+	return sha256Hex(token)
+}
+
+// DeleteCalendarFeed turns off the calendar feed of a user, used when the
+// password changes so a leaked feed URL does not outlive it. A new feed
+// has to be created to subscribe again.
+func DeleteCalendarFeed(db *gorm.DB, userID uint) error {
+	return db.Where("user_id = ?", userID).Delete(&models.CalendarFeed{}).Error
+}
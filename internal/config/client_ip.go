@@ -0,0 +1,27 @@
+package config
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TrustProxy makes ClientIP read X-Forwarded-For, for deployments behind a
+// reverse proxy such as Railway's
+var TrustProxy = os.Getenv("TRUST_PROXY") == "true"
+
+// ClientIP returns the address the request came from
+func ClientIP(r *http.Request) string {
+	if TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
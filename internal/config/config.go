@@ -1,23 +1,69 @@
 package config
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"os"
 	"strings"
 
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
 	"github.com/golang-jwt/jwt/v4"
 	"gorm.io/gorm"
 )
 
 var JwtKey = []byte(os.Getenv("JWT_KEY"))
 
+// MinJwtKeyLength is the shortest JWT_KEY accepted in production
+const MinJwtKeyLength = 32
+
+// AppEnv is "production" on deployed servers
+var AppEnv = os.Getenv("APP_ENV")
+
+// AppURL is the base URL used for links in emails
+var AppURL = os.Getenv("APP_URL")
+
+// RequireEmailVerification stops users with an unverified email from logging in
+var RequireEmailVerification = os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+
 func init() {
-	if len(JwtKey) == 0 {
-		JwtKey = []byte("bMLRrApp4zf6qzWoMa-brT6HMwG5Lp5VY8l1Y-K34Xwsm8B3-kB9p7pcRoWKP8jafaTuCylxPMllgz6uFT6zfQ") // Fallback key
+	if len(JwtKey) == 0 && JwtAlgorithm == "HS256" && !IsProduction() {
+		// Random key for local development, tokens stop working on restart
+		JwtKey = make([]byte, MinJwtKeyLength)
+		if _, err := rand.Read(JwtKey); err != nil {
+			log.Fatalf("Failed to generate JWT key: %v", err)
+		}
+		log.Println("JWT_KEY is not set, using a random key for this run")
+	}
+	if AppURL == "" {
+		AppURL = "http://localhost:8080"
+	}
+}
+
+func IsProduction() bool {
+	return AppEnv == "production"
+}
+
+// CheckProductionSettings refuses settings that are unsafe on a deployed server
+func CheckProductionSettings() error {
+	if !IsProduction() {
+		return nil
+	}
+	if JwtAlgorithm == "HS256" && len(JwtKey) < MinJwtKeyLength {
+		return fmt.Errorf("JWT_KEY must be at least %d bytes long in production", MinJwtKeyLength)
+	}
+	return nil
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
 }
 
 func SendJSONResponse(w http.ResponseWriter, v interface{}) {
@@ -26,10 +72,39 @@ func SendJSONResponse(w http.ResponseWriter, v interface{}) {
 	encoder.Encode(v)
 }
 
+// AccountBlocked returns why a user may not log in or use the API, if an
+// admin disabled the account or it is waiting to be deleted
+func AccountBlocked(user models.User) error {
+	if user.DisabledAt != nil {
+		return errors.New("Account has been disabled")
+	}
+	if user.DeletionScheduledAt != nil {
+		return errors.New("Account is scheduled for deletion, use the link sent by email to restore it")
+	}
+	return nil
+}
+
+// ChangePasswordPath is the only route open to users that must change their password
+const ChangePasswordPath = "/users/change-password"
+
+// Data export routes stay open to accounts scheduled for deletion
+const (
+	DataExportPath        = "/users/export"
+	DataExportsPathPrefix = "/users/exports/"
+)
+
+func isDataExportPath(path string) bool {
+	return path == DataExportPath || strings.HasPrefix(path, DataExportsPathPrefix)
+}
+
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
 	jwt.RegisteredClaims
+
+	// Set when the request used a personal access token instead of a JWT
+	PersonalTokenID uint     `json:"-"`
+	Scopes          []string `json:"-"`
 }
 
 func Authenticate(r *http.Request, db *gorm.DB) (*Claims, error) {
@@ -40,43 +115,100 @@ func Authenticate(r *http.Request, db *gorm.DB) (*Claims, error) {
 	}
 	tokenString := authHeaderParts[1]
 
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return JwtKey, nil
-	})
+	if strings.HasPrefix(tokenString, PersonalTokenPrefix) {
+		claims, err := authenticatePersonalToken(r, db, tokenString)
+		if err != nil {
+			return nil, err
+		}
 
-	if err != nil {
-		return nil, errors.New("Invalid token")
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			return nil, errors.New("User not found")
+		}
+		claims.Email = user.Email
+
+		if err := checkAccountRestrictions(r, db, user); err != nil {
+			return nil, err
+		}
+
+		return claims, nil
 	}
 
-	if !token.Valid {
-		return nil, errors.New("Invalid token")
+	claims := &Claims{}
+	if err := ParseToken(tokenString, claims); err != nil {
+		return nil, err
 	}
 
 	// Fetch user based on the email from claims
 	var user models.User
-	if err := db.Where("email = ?", claims.Email).First(&user).Error; err != nil {
+	if err := db.Where("LOWER(email) = ?", NormalizeEmail(claims.Email)).First(&user).Error; err != nil {
 		return nil, errors.New("User not found")
 	}
 
 	// Set the correct UserID in the claims
 	claims.UserID = user.ID
 
+	// The session behind the token must still be active
+	if claims.ID == "" || !sessionActive(db, claims.ID, user.ID) {
+		return nil, errors.New("Session has expired or been revoked")
+	}
+
+	if err := checkAccountRestrictions(r, db, user); err != nil {
+		return nil, err
+	}
+
 	return claims, nil
 }
 
-func AuthenticateAndAuthorize(r *http.Request, db *gorm.DB) (bool, error) {
+// checkAccountRestrictions keeps users that still have to secure their
+// account away from everything else
+func checkAccountRestrictions(r *http.Request, db *gorm.DB, user models.User) error {
+	if err := AccountBlocked(user); err != nil {
+		// Accounts waiting to be deleted can still take their data with them
+		if user.DisabledAt != nil || user.DeletionScheduledAt == nil || !isDataExportPath(r.URL.Path) {
+			return err
+		}
+	}
+
+	// Users with a temporary password can only change it
+	if user.MustChangePassword && r.URL.Path != ChangePasswordPath {
+		return errors.New("Password must be changed before continuing, use " + ChangePasswordPath)
+	}
+
+	// Admins, and every role with more than member permissions, can be
+	// required to set up two-factor authentication first
+	if policy.IsPrivileged(db, user.Role) && !user.TwoFactorEnabled && !strings.HasPrefix(r.URL.Path, TwoFactorPathPrefix) && RequireAdminTwoFactor(db) {
+		return errors.New("Two-factor authentication must be set up before continuing, use " + TwoFactorPathPrefix + "setup")
+	}
+
+	return nil
+}
+
+// AuthenticateActor authenticates the request and loads the user making it,
+// ready for permission checks
+func AuthenticateActor(r *http.Request, db *gorm.DB) (*Claims, policy.Actor, error) {
 	claims, err := Authenticate(r, db)
 	if err != nil {
-		return false, err
+		return nil, policy.Actor{}, err
 	}
 
 	var user models.User
-	if err := db.Where("email = ?", claims.Email).First(&user).Error; err != nil {
-		return false, errors.New("User not found")
+	if err := db.First(&user, claims.UserID).Error; err != nil {
+		return nil, policy.Actor{}, errors.New("User not found")
+	}
+
+	return claims, policy.Actor{User: user, Restricted: !claims.HasScope(ScopeAdmin)}, nil
+}
+
+// AuthenticateAndAuthorize authenticates the request and checks that the
+// user has the permission
+func AuthenticateAndAuthorize(r *http.Request, db *gorm.DB, permission policy.Permission) (bool, error) {
+	_, actor, err := AuthenticateActor(r, db)
+	if err != nil {
+		return false, err
 	}
 
-	if user.Role != "admin" {
+	if !policy.Has(db, actor, permission) {
 		return false, errors.New("Unauthorized access")
 	}
 
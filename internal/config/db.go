@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/search"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/timetracking"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"os"
@@ -46,5 +48,16 @@ func ConnectDB() (*gorm.DB, error) {
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(&models.User{}, &models.Category{}, &models.Task{})
+	if err := db.AutoMigrate(&models.User{}, &models.Category{}, &models.Sprint{}, &models.RecurringTask{}, &models.Task{}, &models.Comment{}, &models.NotificationPreference{}, &models.Notification{}, &models.UserToken{}, &models.Session{}, &models.UserIdentity{}, &models.OIDCLoginState{}, &models.PersonalAccessToken{}, &models.RecoveryCode{}, &models.Setting{}, &models.LoginAttempt{}, &models.Role{}, &models.RolePermission{}, &models.DataExport{}, &models.Label{}, &models.ChecklistItem{}, &models.SavedView{}, &models.TaskTransition{}, &models.TimeEntry{}, &models.TaskTemplate{}, &models.CalendarFeed{}); err != nil {
+		return err
+	}
+
+	// Indexes gorm cannot describe with struct tags
+	if err := ensureUserEmailIndex(db); err != nil {
+		return err
+	}
+	if err := timetracking.EnsureIndexes(db); err != nil {
+		return err
+	}
+	return search.EnsureIndexes(db)
 }
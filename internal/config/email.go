@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// NormalizeEmail is the form emails are stored and looked up in, so
+// A@x.com and a@x.com are the same user. Look users up with
+// LOWER(email) = NormalizeEmail(email).
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ValidateEmail rejects addresses users cannot have. The reserved .invalid
+// domain is kept for placeholder accounts.
+func ValidateEmail(email string) error {
+	if email == "" {
+		return errors.New("Email is required")
+	}
+	if strings.HasSuffix(NormalizeEmail(email), ".invalid") {
+		return errors.New("Email addresses on .invalid domains cannot be used")
+	}
+	return nil
+}
+
+// ensureUserEmailIndex normalizes the emails stored before they were and
+// makes them unique regardless of case. Emails that only differ in case
+// have to be renamed by hand first.
+func ensureUserEmailIndex(db *gorm.DB) error {
+	var duplicates []string
+	if err := db.Model(&models.User{}).Group("LOWER(TRIM(email))").Having("COUNT(*) > 1").Pluck("LOWER(TRIM(email))", &duplicates).Error; err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("More than one user has the email %s in different case, rename all but one of them", strings.Join(duplicates, ", "))
+	}
+
+	if err := db.Model(&models.User{}).Where("email <> LOWER(TRIM(email))").Update("email", gorm.Expr("LOWER(TRIM(email))")).Error; err != nil {
+		return err
+	}
+	return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users (LOWER(email))").Error
+}
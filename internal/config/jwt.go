@@ -0,0 +1,245 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// JwtAlgorithm is the algorithm new tokens are signed with: HS256, RS256 or EdDSA
+	JwtAlgorithm = getEnv("JWT_ALGORITHM", "HS256")
+	JwtIssuer    = getEnv("JWT_ISSUER", "kanban-board")
+	JwtAudience  = getEnv("JWT_AUDIENCE", "kanban-board")
+)
+
+// SigningKey is a key that tokens are signed or verified with. Keys kept
+// only for verification during a rotation have no private part.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private interface{}
+	Public  interface{}
+}
+
+type keySet struct {
+	active *SigningKey
+	byID   map[string]*SigningKey
+}
+
+var signingKeys *keySet
+
+// LoadSigningKeys sets up the keys used to sign and verify tokens.
+//
+// HS256 uses JWT_KEY. RS256 and EdDSA sign with the PEM private key in
+// JWT_PRIVATE_KEY_FILE, identified by JWT_KEY_ID. Old public keys that
+// should still be accepted while their tokens expire are listed in
+// JWT_PUBLIC_KEY_FILES as comma separated kid=path pairs.
+func LoadSigningKeys() error {
+	keys := &keySet{byID: make(map[string]*SigningKey)}
+
+	switch JwtAlgorithm {
+	case "HS256":
+		if len(JwtKey) == 0 {
+			return errors.New("JWT_KEY is required for HS256")
+		}
+		sum := sha256.Sum256(JwtKey)
+		keys.active = &SigningKey{
+			ID:      "hs-" + hex.EncodeToString(sum[:4]),
+			Method:  jwt.SigningMethodHS256,
+			Private: JwtKey,
+			Public:  JwtKey,
+		}
+	case "RS256", "EdDSA":
+		key, err := loadPrivateKey(os.Getenv("JWT_PRIVATE_KEY_FILE"), os.Getenv("JWT_KEY_ID"))
+		if err != nil {
+			return err
+		}
+		if key.Method.Alg() != JwtAlgorithm {
+			return fmt.Errorf("JWT_PRIVATE_KEY_FILE holds a %s key, expected %s", key.Method.Alg(), JwtAlgorithm)
+		}
+		keys.active = key
+	default:
+		return fmt.Errorf("unsupported JWT_ALGORITHM %q", JwtAlgorithm)
+	}
+	keys.byID[keys.active.ID] = keys.active
+
+	for _, entry := range strings.Split(os.Getenv("JWT_PUBLIC_KEY_FILES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("JWT_PUBLIC_KEY_FILES entry %q must be kid=path", entry)
+		}
+		key, err := loadPublicKey(path, kid)
+		if err != nil {
+			return err
+		}
+		if _, exists := keys.byID[kid]; !exists {
+			keys.byID[kid] = key
+		}
+	}
+
+	signingKeys = keys
+	return nil
+}
+
+func loadPrivateKey(path, kid string) (*SigningKey, error) {
+	if path == "" {
+		return nil, errors.New("JWT_PRIVATE_KEY_FILE is required for " + JwtAlgorithm)
+	}
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kid}
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(pem); err == nil {
+		key.Method, key.Private, key.Public = jwt.SigningMethodRS256, rsaKey, &rsaKey.PublicKey
+	} else if edKey, err := jwt.ParseEdPrivateKeyFromPEM(pem); err == nil {
+		key.Method, key.Private, key.Public = jwt.SigningMethodEdDSA, edKey, edKey.(ed25519.PrivateKey).Public()
+	} else {
+		return nil, fmt.Errorf("%s is not an RSA or Ed25519 private key", path)
+	}
+
+	if key.ID == "" {
+		key.ID, err = keyThumbprint(key.Public)
+	}
+	return key, err
+}
+
+func loadPublicKey(path, kid string) (*SigningKey, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{ID: kid}
+	if rsaKey, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+		key.Method, key.Public = jwt.SigningMethodRS256, rsaKey
+	} else if edKey, err := jwt.ParseEdPublicKeyFromPEM(pem); err == nil {
+		key.Method, key.Public = jwt.SigningMethodEdDSA, edKey
+	} else {
+		return nil, fmt.Errorf("%s is not an RSA or Ed25519 public key", path)
+	}
+	return key, nil
+}
+
+// keyThumbprint derives a key ID from the public key
+func keyThumbprint(public interface{}) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func currentKeys() *keySet {
+	if signingKeys == nil {
+		if err := LoadSigningKeys(); err != nil {
+			panic(err)
+		}
+	}
+	return signingKeys
+}
+
+// NewRegisteredClaims fills the standard claims of a token issued by this server
+func NewRegisteredClaims(id string, expiresAt time.Time) jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{
+		ID:        id,
+		Issuer:    JwtIssuer,
+		Audience:  jwt.ClaimStrings{JwtAudience},
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	}
+}
+
+// SignToken signs claims with the active key and sets its kid header
+func SignToken(claims jwt.Claims) (string, error) {
+	active := currentKeys().active
+
+	token := jwt.NewWithClaims(active.Method, claims)
+	token.Header["kid"] = active.ID
+	return token.SignedString(active.Private)
+}
+
+type verifiableClaims interface {
+	jwt.Claims
+	VerifyIssuer(cmp string, req bool) bool
+	VerifyAudience(cmp string, req bool) bool
+}
+
+// ParseToken verifies a token signed by SignToken and fills claims. The key
+// is picked by the kid header and the token must use that key's algorithm.
+func ParseToken(tokenString string, claims verifiableClaims) error {
+	keys := currentKeys()
+
+	methods := []string{}
+	for _, key := range keys.byID {
+		methods = append(methods, key.Method.Alg())
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := keys.byID[kid]
+		if !ok {
+			return nil, errors.New("unknown signing key")
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.Public, nil
+	}, jwt.WithValidMethods(methods))
+	if err != nil || !token.Valid {
+		return errors.New("Invalid token")
+	}
+
+	if !claims.VerifyIssuer(JwtIssuer, true) || !claims.VerifyAudience(JwtAudience, true) {
+		return errors.New("Invalid token")
+	}
+
+	return nil
+}
+
+// JWKS returns the public keys other services can verify our tokens with.
+// Shared HS256 secrets are never published.
+func JWKS() map[string]interface{} {
+	jwks := []map[string]string{}
+	for _, key := range currentKeys().byID {
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": key.Method.Alg(),
+				"kid": key.ID,
+				"n":   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks = append(jwks, map[string]string{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"use": "sig",
+				"alg": key.Method.Alg(),
+				"kid": key.ID,
+				"x":   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+	return map[string]interface{}{"keys": jwks}
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// PersonalTokenPrefix starts every personal access token, so they are easy
+// to tell apart from JWTs and to find in leaked code.
+const PersonalTokenPrefix = "kbp_"
+
+// Scopes a personal access token can be given
+const (
+	ScopeRead  = "read"  // GET requests only
+	ScopeWrite = "write" // every request method
+	ScopeAdmin = "admin" // use the admin rights of the owner
+)
+
+var PersonalTokenScopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// GeneratePersonalToken returns a new token and the hash to store for it
+func GeneratePersonalToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashPersonalToken(token), nil
+}
+
+func HashPersonalToken(token string) string {
+	return sha256Hex(token)
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// HasScope reports whether the request was made with a personal access
+// token holding the scope. Requests made with a login token have every scope.
+func (c *Claims) HasScope(scope string) bool {
+	if c.PersonalTokenID == 0 {
+		return true
+	}
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticatePersonalToken checks a personal access token and records that
+// it was used.
+func authenticatePersonalToken(r *http.Request, db *gorm.DB, tokenString string) (*Claims, error) {
+	var token models.PersonalAccessToken
+	if err := db.Where("token_hash = ? AND revoked_at IS NULL", HashPersonalToken(tokenString)).First(&token).Error; err != nil {
+		return nil, errors.New("Invalid token")
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && token.ExpiresAt.Before(now) {
+		return nil, errors.New("Token has expired")
+	}
+
+	claims := &Claims{
+		UserID:          token.UserID,
+		PersonalTokenID: token.ID,
+		Scopes:          strings.Fields(token.Scopes),
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead && !claims.HasScope(ScopeWrite) {
+		return nil, errors.New("Token does not have the write scope")
+	}
+	if !claims.HasScope(ScopeRead) && !claims.HasScope(ScopeWrite) {
+		return nil, errors.New("Token does not have the read scope")
+	}
+
+	db.Model(&token).UpdateColumn("last_used_at", now)
+
+	return claims, nil
+}
+
+// RevokePersonalTokens revokes every personal access token of a user, used
+// when the password changes so a leaked token does not outlive it.
+func RevokePersonalTokens(db *gorm.DB, userID uint) error {
+	return db.Model(&models.PersonalAccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
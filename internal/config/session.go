@@ -0,0 +1,42 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// CreateSession starts a new session for a user that lasts until expiresAt.
+func CreateSession(db *gorm.DB, userID uint, expiresAt time.Time) (models.Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return models.Session{}, err
+	}
+
+	session := models.Session{
+		ID:        hex.EncodeToString(id),
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	err := db.Create(&session).Error
+	return session, err
+}
+
+// RevokeSessions ends every active session of a user except exceptID. Pass an
+// empty exceptID to end all of them.
+func RevokeSessions(db *gorm.DB, userID uint, exceptID string) error {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, exceptID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func sessionActive(db *gorm.DB, id string, userID uint) bool {
+	var count int64
+	db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, userID, time.Now()).
+		Count(&count)
+	return count > 0
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetSetting returns the value of a setting, or "" when it was never set
+func GetSetting(db *gorm.DB, key string) string {
+	var setting models.Setting
+	if err := db.Where("key = ?", key).First(&setting).Error; err != nil {
+		return ""
+	}
+	return setting.Value
+}
+
+// SetSetting stores the value of a setting
+func SetSetting(db *gorm.DB, key, value string) error {
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.Setting{Key: key, Value: value}).Error
+}
+
+// RequireAdminTwoFactor reports whether admins must use two-factor authentication
+func RequireAdminTwoFactor(db *gorm.DB) bool {
+	return GetSetting(db, models.SettingRequireAdminTwoFactor) == "true"
+}
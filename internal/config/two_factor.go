@@ -0,0 +1,62 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+)
+
+const twoFactorChallengePurpose = "two_factor_login"
+
+// TwoFactorPathPrefix holds the routes open to admins that still have to set
+// up two-factor authentication
+const TwoFactorPathPrefix = "/users/2fa/"
+
+// TwoFactorIssuer is the account name shown in authenticator apps
+var TwoFactorIssuer = getEnv("TOTP_ISSUER", "Kanban Board")
+
+// IssueTwoFactorChallenge returns a short lived token proving the user got
+// the password right. It is traded for a login token together with a code.
+func IssueTwoFactorChallenge(user models.User) (string, error) {
+	claims := &UserTokenClaims{
+		UserID:           user.ID,
+		Purpose:          twoFactorChallengePurpose,
+		RegisteredClaims: NewRegisteredClaims("", time.Now().Add(5*time.Minute)),
+	}
+	return SignToken(claims)
+}
+
+// ParseTwoFactorChallenge returns the user a challenge was issued for
+func ParseTwoFactorChallenge(tokenString string) (uint, error) {
+	claims := &UserTokenClaims{}
+	if err := ParseToken(tokenString, claims); err != nil || claims.Purpose != twoFactorChallengePurpose {
+		return 0, errors.New("Invalid or expired challenge token")
+	}
+	return claims.UserID, nil
+}
+
+// GenerateRecoveryCodes returns n random codes like "k3x7p-7hq2m"
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(base32.StdEncoding.EncodeToString(b))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode hashes a recovery code the way it is stored, ignoring
+// case, spaces and dashes
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	return sha256Hex(code)
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/golang-jwt/jwt/v4"
+	"gorm.io/gorm"
+)
+
+type UserTokenClaims struct {
+	UserID  uint   `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// IssueUserToken creates a signed, single-use token for the given purpose
+// that expires after ttl.
+func IssueUserToken(db *gorm.DB, user models.User, purpose string, ttl time.Duration) (string, error) {
+	record := models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := db.Create(&record).Error; err != nil {
+		return "", err
+	}
+
+	claims := &UserTokenClaims{
+		UserID:           user.ID,
+		Purpose:          purpose,
+		RegisteredClaims: NewRegisteredClaims(strconv.FormatUint(uint64(record.ID), 10), record.ExpiresAt),
+	}
+
+	return SignToken(claims)
+}
+
+// ConsumeUserToken checks a token issued by IssueUserToken and marks it as
+// used, so the same token cannot be used twice.
+func ConsumeUserToken(db *gorm.DB, tokenString, purpose string) (*models.User, error) {
+	claims := &UserTokenClaims{}
+	if err := ParseToken(tokenString, claims); err != nil || claims.Purpose != purpose {
+		return nil, errors.New("Invalid or expired token")
+	}
+
+	result := db.Model(&models.UserToken{}).
+		Where("id = ? AND user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", claims.ID, claims.UserID, purpose, time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, errors.New("Invalid or expired token")
+	}
+
+	var user models.User
+	if err := db.First(&user, claims.UserID).Error; err != nil {
+		return nil, errors.New("User not found")
+	}
+
+	return &user, nil
+}
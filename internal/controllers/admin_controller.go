@@ -0,0 +1,442 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/notifications"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// UnlockUser lifts the login lockout of a user
+func UnlockUser(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !policy.Has(db, actor, policy.UserManage) {
+			http.Error(w, "Unauthorized access", http.StatusUnauthorized)
+			return
+		}
+
+		user, ok := findUserFromPath(w, r, db)
+		if !ok || !canManageUser(w, db, actor, user) {
+			return
+		}
+
+		recordLoginAttempt(db, config.NormalizeEmail(user.Email), &user, config.ClientIP(r), models.LoginUnlocked, "unlocked by "+actor.Email)
+
+		config.SendJSONResponse(w, map[string]string{"message": "User has been successfully unlocked"})
+	}
+}
+
+type GetLoginAttemptsResponse struct {
+	LoginAttempts []models.LoginAttempt `json:"login_attempts"`
+	Page          int                   `json:"page"`
+	Limit         int                   `json:"limit"`
+	Total         int64                 `json:"total"`
+}
+
+// GetLoginAttempts lists the login audit records, newest first. They can be
+// filtered with the email, ip and result query parameters.
+func GetLoginAttempts(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.AuditRead)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		page, limit := parsePagination(r)
+
+		query := db.Model(&models.LoginAttempt{})
+		if email := r.URL.Query().Get("email"); email != "" {
+			query = query.Where("LOWER(email) = ?", config.NormalizeEmail(email))
+		}
+		if ip := r.URL.Query().Get("ip"); ip != "" {
+			query = query.Where("ip = ?", ip)
+		}
+		if result := r.URL.Query().Get("result"); result != "" {
+			query = query.Where("result = ?", result)
+		}
+		query = query.Session(&gorm.Session{})
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		attempts := []models.LoginAttempt{}
+		if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&attempts).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, GetLoginAttemptsResponse{
+			LoginAttempts: attempts,
+			Page:          page,
+			Limit:         limit,
+			Total:         total,
+		})
+	}
+}
+
+// AdminUserResponse is a user as admins see it, without the password hash
+type AdminUserResponse struct {
+	ID                  uint       `json:"id"`
+	FullName            string     `json:"full_name"`
+	Email               string     `json:"email"`
+	Role                string     `json:"role"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at"`
+	MustChangePassword  bool       `json:"must_change_password"`
+	TwoFactorEnabled    bool       `json:"two_factor_enabled"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+func adminUserResponse(user models.User) AdminUserResponse {
+	return AdminUserResponse{
+		ID:                  user.ID,
+		FullName:            user.FullName,
+		Email:               user.Email,
+		Role:                user.Role,
+		EmailVerifiedAt:     user.EmailVerifiedAt,
+		MustChangePassword:  user.MustChangePassword,
+		TwoFactorEnabled:    user.TwoFactorEnabled,
+		DisabledAt:          user.DisabledAt,
+		DeletionScheduledAt: user.DeletionScheduledAt,
+		CreatedAt:           user.CreatedAt,
+		UpdatedAt:           user.UpdatedAt,
+	}
+}
+
+// findUserFromPath loads the user named by the userId route variable and
+// writes the error response when there is none
+func findUserFromPath(w http.ResponseWriter, r *http.Request, db *gorm.DB) (models.User, bool) {
+	var user models.User
+
+	userID, err := strconv.Atoi(mux.Vars(r)["userId"])
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return user, false
+	}
+
+	if err := db.First(&user, userID).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return user, false
+	}
+
+	return user, true
+}
+
+// isLastAdmin reports whether the user is the only enabled admin left. Admins
+// are the users whose role has every permission.
+func isLastAdmin(db *gorm.DB, user models.User) bool {
+	if !policy.Grants(db, user.Role, policy.All) || config.AccountBlocked(user) != nil {
+		return false
+	}
+
+	var otherAdmins int64
+	db.Model(&models.User{}).
+		Where("role IN ? AND disabled_at IS NULL AND deletion_scheduled_at IS NULL AND id <> ?", policy.FullAccessRoles(db), user.ID).
+		Count(&otherAdmins)
+	return otherAdmins == 0
+}
+
+// isLastAdminRole reports whether the role has every permission and no
+// enabled user with another such role is left, so taking full access from
+// the role would leave no admin
+func isLastAdminRole(db *gorm.DB, role string) bool {
+	if !policy.Grants(db, role, policy.All) {
+		return false
+	}
+
+	var otherAdmins int64
+	db.Model(&models.User{}).
+		Where("role IN ? AND role <> ? AND disabled_at IS NULL AND deletion_scheduled_at IS NULL", policy.FullAccessRoles(db), role).
+		Count(&otherAdmins)
+	return otherAdmins == 0
+}
+
+// canManageUser stops the actor from acting on users whose role has
+// permissions the actor lacks, the same rule as for giving roles
+func canManageUser(w http.ResponseWriter, db *gorm.DB, actor policy.Actor, user models.User) bool {
+	if !policy.Covers(db, actor, user.Role) {
+		http.Error(w, "Cannot manage users with permissions you do not have", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+type GetUsersResponse struct {
+	Users []AdminUserResponse `json:"users"`
+	Page  int                 `json:"page"`
+	Limit int                 `json:"limit"`
+	Total int64               `json:"total"`
+}
+
+// GetUsers lists the users. They can be searched by name or email with the
+// search query parameter and filtered with role and disabled.
+func GetUsers(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.UserManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		page, limit := parsePagination(r)
+
+		query := db.Model(&models.User{})
+		if search := strings.TrimSpace(r.URL.Query().Get("search")); search != "" {
+			pattern := "%" + strings.ToLower(search) + "%"
+			query = query.Where("LOWER(full_name) LIKE ? OR LOWER(email) LIKE ?", pattern, pattern)
+		}
+		if role := r.URL.Query().Get("role"); role != "" {
+			query = query.Where("role = ?", role)
+		}
+		switch r.URL.Query().Get("disabled") {
+		case "true":
+			query = query.Where("disabled_at IS NOT NULL")
+		case "false":
+			query = query.Where("disabled_at IS NULL")
+		}
+		query = query.Session(&gorm.Session{})
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var users []models.User
+		if err := query.Order("id").Offset((page - 1) * limit).Limit(limit).Find(&users).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := GetUsersResponse{
+			Users: []AdminUserResponse{},
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		}
+		for _, user := range users {
+			response.Users = append(response.Users, adminUserResponse(user))
+		}
+
+		config.SendJSONResponse(w, response)
+	}
+}
+
+// GetUser shows one user
+func GetUser(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.UserManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		user, ok := findUserFromPath(w, r, db)
+		if !ok {
+			return
+		}
+
+		config.SendJSONResponse(w, adminUserResponse(user))
+	}
+}
+
+type GetUserTasksResponse struct {
+	Tasks []GetTasksResponse `json:"tasks"`
+	Page  int                `json:"page"`
+	Limit int                `json:"limit"`
+	Total int64              `json:"total"`
+}
+
+// GetUserTasks lists the tasks a user owns or is assigned to
+func GetUserTasks(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !policy.Has(db, actor, policy.UserManage) || !policy.Has(db, actor, policy.TaskReadAny) {
+			http.Error(w, "Unauthorized access", http.StatusUnauthorized)
+			return
+		}
+
+		user, ok := findUserFromPath(w, r, db)
+		if !ok {
+			return
+		}
+
+		page, limit := parsePagination(r)
+		query := db.Model(&models.Task{}).
+			Where("user_id = ? OR assignee_id = ?", user.ID, user.ID).
+			Session(&gorm.Session{})
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		var tasks []models.Task
+		if err := query.Preload("User").Order("id").Offset((page - 1) * limit).Limit(limit).Find(&tasks).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		taskResponses, err := taskListResponse(db, tasks)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := GetUserTasksResponse{
+			Tasks: taskResponses,
+			Page:  page,
+			Limit: limit,
+			Total: total,
+		}
+		if response.Tasks == nil {
+			response.Tasks = []GetTasksResponse{}
+		}
+
+		config.SendJSONResponse(w, response)
+	}
+}
+
+// DisableUser stops a user from logging in and logs them out everywhere.
+// Their personal access tokens are rejected while the account is disabled.
+func DisableUser(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !policy.Has(db, actor, policy.UserManage) {
+			http.Error(w, "Unauthorized access", http.StatusUnauthorized)
+			return
+		}
+
+		user, ok := findUserFromPath(w, r, db)
+		if !ok || !canManageUser(w, db, actor, user) {
+			return
+		}
+
+		if user.ID == claims.UserID {
+			http.Error(w, "You cannot disable your own account", http.StatusBadRequest)
+			return
+		}
+		if isLastAdmin(db, user) {
+			http.Error(w, "Cannot disable the last admin", http.StatusConflict)
+			return
+		}
+
+		if user.DisabledAt == nil {
+			if err := db.Model(&user).Update("disabled_at", time.Now()).Error; err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := config.RevokeSessions(db, user.ID, ""); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, adminUserResponse(user))
+	}
+}
+
+// EnableUser lets a disabled user log in again
+func EnableUser(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !policy.Has(db, actor, policy.UserManage) {
+			http.Error(w, "Unauthorized access", http.StatusUnauthorized)
+			return
+		}
+
+		user, ok := findUserFromPath(w, r, db)
+		if !ok || !canManageUser(w, db, actor, user) {
+			return
+		}
+
+		if err := db.Model(&user).Update("disabled_at", nil).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, adminUserResponse(user))
+	}
+}
+
+// ForcePasswordReset logs a user out everywhere and makes them change their
+// password on the next login. A reset link is emailed in case they do not
+// know the current one.
+func ForcePasswordReset(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !policy.Has(db, actor, policy.UserManage) {
+			http.Error(w, "Unauthorized access", http.StatusUnauthorized)
+			return
+		}
+
+		user, ok := findUserFromPath(w, r, db)
+		if !ok || !canManageUser(w, db, actor, user) {
+			return
+		}
+
+		if err := db.Model(&user).Update("must_change_password", true).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := config.RevokeSessions(db, user.ID, ""); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := config.RevokePersonalTokens(db, user.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := config.DeleteCalendarFeed(db, user.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		token, err := config.IssueUserToken(db, user, models.TokenPurposeResetPassword, 24*time.Hour)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		notifications.AccountEmail(notifications.EventPasswordReset, user, config.AppURL+"/reset-password?token="+url.QueryEscape(token))
+
+		config.SendJSONResponse(w, map[string]string{"message": "User must change their password on the next login"})
+	}
+}
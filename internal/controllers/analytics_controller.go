@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/analytics"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAnalyticsDays  = 30
+	maxAnalyticsDays      = 366
+	defaultAnalyticsWeeks = 12
+	maxAnalyticsWeeks     = 104
+)
+
+// analyticsScope authenticates the user and returns the tasks they can see
+func analyticsScope(w http.ResponseWriter, r *http.Request, db *gorm.DB) (analytics.Scope, bool) {
+	claims, actor, err := config.AuthenticateActor(r, db)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return analytics.Scope{}, false
+	}
+	return analytics.Scope{UserID: claims.UserID, AllTasks: policy.Has(db, actor, policy.TaskReadAny)}, true
+}
+
+// parseAnalyticsRange reads from and to, defaulting to the last 30 days
+func parseAnalyticsRange(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	to := time.Now()
+	if value := r.URL.Query().Get("to"); value != "" {
+		t, err := parseFilterTime(value, true)
+		if err != nil {
+			http.Error(w, "to must be a date or RFC 3339 time", http.StatusBadRequest)
+			return time.Time{}, time.Time{}, false
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -defaultAnalyticsDays)
+	if value := r.URL.Query().Get("from"); value != "" {
+		t, err := parseFilterTime(value, false)
+		if err != nil {
+			http.Error(w, "from must be a date or RFC 3339 time", http.StatusBadRequest)
+			return time.Time{}, time.Time{}, false
+		}
+		from = t
+	}
+
+	if from.After(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	if to.Sub(from) > maxAnalyticsDays*24*time.Hour {
+		http.Error(w, "The range can be at most 366 days", http.StatusBadRequest)
+		return time.Time{}, time.Time{}, false
+	}
+	return from, to, true
+}
+
+// GetCumulativeFlow returns the number of tasks in each category at the end
+// of every day between from and to
+func GetCumulativeFlow(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := analyticsScope(w, r, db)
+		if !ok {
+			return
+		}
+		from, to, ok := parseAnalyticsRange(w, r)
+		if !ok {
+			return
+		}
+
+		days, err := analytics.CumulativeFlow(db, scope, from, to)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]interface{}{"days": days})
+	}
+}
+
+// GetCycleTime returns the lead and cycle times of the tasks done between
+// from and to
+func GetCycleTime(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := analyticsScope(w, r, db)
+		if !ok {
+			return
+		}
+		from, to, ok := parseAnalyticsRange(w, r)
+		if !ok {
+			return
+		}
+
+		report, err := analytics.CycleTime(db, scope, from, to)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, report)
+	}
+}
+
+// GetThroughput returns the number of tasks done in each of the last weeks
+func GetThroughput(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := analyticsScope(w, r, db)
+		if !ok {
+			return
+		}
+
+		weeks := defaultAnalyticsWeeks
+		if value := r.URL.Query().Get("weeks"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > maxAnalyticsWeeks {
+				http.Error(w, "weeks must be between 1 and 104", http.StatusBadRequest)
+				return
+			}
+			weeks = n
+		}
+
+		result, err := analytics.Throughput(db, scope, weeks, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]interface{}{"weeks": result})
+	}
+}
+
+// GetAgingWIP returns the open tasks with how long they have been in progress
+func GetAgingWIP(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scope, ok := analyticsScope(w, r, db)
+		if !ok {
+			return
+		}
+
+		report, err := analytics.AgingWIP(db, scope, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, report)
+	}
+}
@@ -0,0 +1,211 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+)
+
+// authzCase is a request an actor may or may not make on a resource
+type authzCase struct {
+	name    string
+	method  string
+	path    string
+	body    interface{}
+	actor   string
+	allowed bool
+}
+
+// authzActors are the users of an authorization test. owner owns the task
+// under test and assignee is assigned to it; other is an unrelated member.
+type authzActors struct {
+	app      *testApp
+	users    map[string]models.User
+	tokens   map[string]string
+	category models.Category
+}
+
+func newAuthzActors(t *testing.T) *authzActors {
+	app := newTestApp(t)
+	app.createUser("admin@example.com", policy.RoleAdmin)
+	app.createRole("reader", append([]policy.Permission{policy.TaskReadAny}, policy.BuiltinRoles[policy.RoleMember]...)...)
+	app.createRole("editor", append([]policy.Permission{policy.TaskUpdateAny, policy.TaskDeleteAny, policy.CommentCreateAny}, policy.BuiltinRoles[policy.RoleMember]...)...)
+	app.createRole("planner", append([]policy.Permission{policy.CategoryManage}, policy.BuiltinRoles[policy.RoleMember]...)...)
+
+	actors := &authzActors{app: app, users: map[string]models.User{}, tokens: map[string]string{}, category: app.createCategory("Todo")}
+	for name, role := range map[string]string{
+		"owner":    policy.RoleMember,
+		"assignee": policy.RoleMember,
+		"other":    policy.RoleMember,
+		"reader":   "reader",
+		"editor":   "editor",
+		"planner":  "planner",
+	} {
+		user := app.createUser(name+"@example.com", role)
+		actors.users[name] = user
+		actors.tokens[name] = app.tokenFor(user)
+	}
+	return actors
+}
+
+// task creates a task owned by owner and assigned to assignee
+func (a *authzActors) task() models.Task {
+	a.app.t.Helper()
+
+	assigneeID := a.users["assignee"].ID
+	task := models.Task{Title: "Task", CategoryID: a.category.ID, UserID: a.users["owner"].ID, AssigneeID: &assigneeID}
+	if err := a.app.db.Create(&task).Error; err != nil {
+		a.app.t.Fatalf("create task: %v", err)
+	}
+	return task
+}
+
+// run makes each request as its actor and checks it is allowed or denied.
+// path may use %d for a fresh resource created by newResource.
+func (a *authzActors) run(t *testing.T, cases []authzCase, newResource func() uint) {
+	for _, tc := range cases {
+		t.Run(tc.actor+" "+tc.name, func(t *testing.T) {
+			path := tc.path
+			if newResource != nil {
+				path = fmt.Sprintf(tc.path, newResource())
+			}
+			rec := a.app.do(tc.method, path, tc.body, a.tokens[tc.actor])
+			denied := rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden
+			if tc.allowed && (denied || rec.Code >= 400) {
+				t.Errorf("got %d, want it allowed: %s", rec.Code, rec.Body.String())
+			}
+			if !tc.allowed && !denied {
+				t.Errorf("got %d, want 401 or 403", rec.Code)
+			}
+		})
+	}
+}
+
+func TestTaskRoutesCheckTheActorOnTheTask(t *testing.T) {
+	actors := newAuthzActors(t)
+	update := map[string]string{"title": "Changed", "description": "Changed"}
+	status := map[string]bool{"status": true}
+	category := map[string]uint{"category_id": actors.category.ID}
+
+	var cases []authzCase
+	for actor, allowed := range map[string]bool{"owner": true, "assignee": false, "other": false, "reader": false, "editor": true} {
+		cases = append(cases,
+			authzCase{"updates the task", http.MethodPut, "/tasks/%d", update, actor, allowed},
+			authzCase{"updates the status", http.MethodPatch, "/tasks/update-status/%d", status, actor, allowed},
+			authzCase{"moves the task", http.MethodPatch, "/tasks/update-category/%d", category, actor, allowed},
+			authzCase{"deletes the task", http.MethodDelete, "/tasks/%d", nil, actor, allowed},
+		)
+	}
+	actors.run(t, cases, func() uint { return actors.task().ID })
+}
+
+func TestTaskListsOnlyShowTasksOfTheActor(t *testing.T) {
+	actors := newAuthzActors(t)
+	task := actors.task()
+
+	for actor, visible := range map[string]bool{"owner": true, "assignee": true, "other": false} {
+		t.Run(actor, func(t *testing.T) {
+			var tasks []struct {
+				ID uint `json:"id"`
+			}
+			rec := actors.app.do(http.MethodGet, "/tasks", nil, actors.tokens[actor])
+			if err := json.NewDecoder(rec.Body).Decode(&tasks); err != nil {
+				t.Fatalf("list tasks returned %d: %s", rec.Code, rec.Body.String())
+			}
+			found := false
+			for _, listed := range tasks {
+				found = found || listed.ID == task.ID
+			}
+			if found != visible {
+				t.Errorf("task listed: %v, want %v", found, visible)
+			}
+		})
+	}
+}
+
+func TestCommentRoutesCheckTheActorOnTheTask(t *testing.T) {
+	actors := newAuthzActors(t)
+	comment := map[string]string{"body": "A comment"}
+
+	var cases []authzCase
+	for actor, allowed := range map[string]bool{"owner": true, "assignee": true, "other": false, "reader": false, "editor": true} {
+		cases = append(cases, authzCase{"comments on the task", http.MethodPost, "/tasks/%d/comments", comment, actor, allowed})
+	}
+	for actor, allowed := range map[string]bool{"owner": true, "assignee": true, "other": false, "reader": true, "editor": false} {
+		cases = append(cases, authzCase{"reads the comments", http.MethodGet, "/tasks/%d/comments", nil, actor, allowed})
+	}
+	actors.run(t, cases, func() uint { return actors.task().ID })
+}
+
+func TestCategoryRoutesNeedCategoryManage(t *testing.T) {
+	actors := newAuthzActors(t)
+	category := map[string]string{"type": "Doing"}
+
+	var cases []authzCase
+	for actor, allowed := range map[string]bool{"owner": false, "other": false, "editor": false, "planner": true} {
+		cases = append(cases,
+			authzCase{"updates a category", http.MethodPatch, "/categories/%d", category, actor, allowed},
+			authzCase{"deletes a category", http.MethodDelete, "/categories/%d", nil, actor, allowed},
+		)
+	}
+	actors.run(t, cases, func() uint { return actors.app.createCategory("Scratch").ID })
+
+	actors.run(t, []authzCase{
+		{"creates a category", http.MethodPost, "/categories", category, "other", false},
+		{"creates a category", http.MethodPost, "/categories", category, "planner", true},
+		{"lists the categories", http.MethodGet, "/categories", nil, "other", true},
+	}, nil)
+}
+
+func TestLabelRoutesNeedCategoryManage(t *testing.T) {
+	actors := newAuthzActors(t)
+
+	actors.run(t, []authzCase{
+		{"creates a label", http.MethodPost, "/labels", map[string]string{"name": "bug"}, "owner", false},
+		{"creates a label", http.MethodPost, "/labels", map[string]string{"name": "bug"}, "editor", false},
+		{"creates a label", http.MethodPost, "/labels", map[string]string{"name": "bug"}, "planner", true},
+		{"lists the labels", http.MethodGet, "/labels", nil, "other", true},
+	}, nil)
+}
+
+func TestSprintRoutesNeedSprintManage(t *testing.T) {
+	actors := newAuthzActors(t)
+	actors.app.createRole("scrum-master", append([]policy.Permission{policy.SprintManage}, policy.BuiltinRoles[policy.RoleMember]...)...)
+	actors.tokens["scrum-master"] = actors.app.tokenFor(actors.app.createUser("scrum@example.com", "scrum-master"))
+	sprint := map[string]string{"name": "Sprint", "start_date": "2030-01-01", "end_date": "2030-01-14"}
+	newSprint := func() uint {
+		sprint := models.Sprint{Name: "Sprint"}
+		if err := actors.app.db.Create(&sprint).Error; err != nil {
+			t.Fatal(err)
+		}
+		return sprint.ID
+	}
+
+	var cases []authzCase
+	for actor, allowed := range map[string]bool{"owner": false, "editor": false, "planner": false, "scrum-master": true} {
+		cases = append(cases,
+			authzCase{"updates a sprint", http.MethodPut, "/sprints/%d", sprint, actor, allowed},
+			authzCase{"deletes a sprint", http.MethodDelete, "/sprints/%d", nil, actor, allowed},
+		)
+	}
+	for actor := range map[string]bool{"other": true, "scrum-master": true} {
+		cases = append(cases, authzCase{"reads the burndown", http.MethodGet, "/sprints/%d/burndown", nil, actor, true})
+	}
+	actors.run(t, cases, newSprint)
+
+	// The owner of a task cannot plan it, only sprint managers can
+	var planning []authzCase
+	for actor, allowed := range map[string]bool{"owner": false, "scrum-master": true} {
+		task := actors.task()
+		planning = append(planning, authzCase{"plans a task", http.MethodPost, "/sprints/%d/tasks", map[string][]uint{"task_ids": {task.ID}}, actor, allowed})
+	}
+	actors.run(t, planning, newSprint)
+	actors.run(t, []authzCase{
+		{"creates a sprint", http.MethodPost, "/sprints", sprint, "owner", false},
+		{"creates a sprint", http.MethodPost, "/sprints", sprint, "scrum-master", true},
+	}, nil)
+}
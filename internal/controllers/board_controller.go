@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/boards"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"gorm.io/gorm"
+)
+
+// maxImportSize is the largest file accepted by the import endpoints
+const maxImportSize = 10 << 20
+
+// ExportBoard downloads the categories and tasks as JSON or, with
+// format=csv, as a flat CSV file
+func ExportBoard(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			http.Error(w, "format must be json or csv", http.StatusBadRequest)
+			return
+		}
+
+		board, err := boards.Export(db, claims.UserID, policy.Has(db, actor, policy.TaskReadAny))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", `attachment; filename="board.csv"`)
+			if err := boards.WriteCSV(w, board); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Disposition", `attachment; filename="board.json"`)
+		config.SendJSONResponse(w, board)
+	}
+}
+
+// ImportBoard creates tasks from a JSON or CSV file sent as the request
+// body. Nothing is created unless every row is valid, and with dry_run=true
+// nothing is created at all.
+func ImportBoard(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if !policy.Has(db, actor, policy.TaskCreate) {
+			http.Error(w, "Unauthorized to create tasks", http.StatusUnauthorized)
+			return
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxImportSize)
+		query := r.URL.Query()
+
+		var rows []boards.Row
+		var rowErrors []boards.RowError
+		switch query.Get("format") {
+		case "", "json":
+			rows, err = boards.ParseJSON(body)
+		case "csv":
+			rows, rowErrors, err = boards.ParseCSV(body, parseColumnMapping(query.Get("map")))
+		default:
+			http.Error(w, "format must be json or csv", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		result, err := boards.Import(db, rows, boards.Options{
+			OwnerID:          claims.UserID,
+			CreateCategories: policy.Has(db, actor, policy.CategoryManage),
+			CreateLabels:     policy.Has(db, actor, policy.CategoryManage),
+			DryRun:           query.Get("dry_run") == "true" || len(rowErrors) > 0,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Rows that could not be parsed are reported with the others
+		if len(rowErrors) > 0 {
+			result.DryRun = query.Get("dry_run") == "true"
+			result.Errors = append(rowErrors, result.Errors...)
+			result.TasksCreated = 0
+			result.CategoriesCreated = []string{}
+			result.LabelsCreated = []string{}
+		}
+
+		sendImportResult(w, result)
+	}
+}
+
+// ImportTrelloBoard creates categories from the lists and tasks from the
+// cards of a Trello board JSON export sent as the request body
+func ImportTrelloBoard(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if !policy.Has(db, actor, policy.TaskCreate) {
+			http.Error(w, "Unauthorized to create tasks", http.StatusUnauthorized)
+			return
+		}
+
+		board, err := boards.ParseTrello(http.MaxBytesReader(w, r.Body, maxImportSize))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rows, unsupported := board.Rows()
+		result, err := boards.Import(db, rows, boards.Options{
+			OwnerID:          claims.UserID,
+			CreateCategories: policy.Has(db, actor, policy.CategoryManage),
+			CreateLabels:     policy.Has(db, actor, policy.CategoryManage),
+			DryRun:           r.URL.Query().Get("dry_run") == "true",
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result.Unsupported = unsupported
+
+		sendImportResult(w, result)
+	}
+}
+
+func sendImportResult(w http.ResponseWriter, result *boards.Result) {
+	if len(result.Errors) > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	} else if !result.DryRun {
+		w.WriteHeader(http.StatusCreated)
+	}
+	config.SendJSONResponse(w, result)
+}
+
+// parseColumnMapping reads a mapping such as "title:Name,category:List"
+func parseColumnMapping(value string) map[string]string {
+	mapping := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		column, header, ok := strings.Cut(pair, ":")
+		if ok {
+			mapping[strings.TrimSpace(column)] = strings.TrimSpace(header)
+		}
+	}
+	return mapping
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/calendar"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type CalendarFeedResponse struct {
+	Enabled       bool       `json:"enabled"`
+	URL           string     `json:"url,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	LastFetchedAt *time.Time `json:"last_fetched_at,omitempty"`
+}
+
+// GetCalendarFeed shows whether the user has a calendar feed. Its URL is
+// only shown when it is created.
+func GetCalendarFeed(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var feed models.CalendarFeed
+		err = db.Where("user_id = ?", claims.UserID).First(&feed).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			config.SendJSONResponse(w, CalendarFeedResponse{})
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, CalendarFeedResponse{Enabled: true, CreatedAt: &feed.CreatedAt, LastFetchedAt: feed.LastFetchedAt})
+	}
+}
+
+// CreateCalendarFeed creates the calendar feed of the user, replacing the
+// previous one so its URL stops working
+func CreateCalendarFeed(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		token, hash, err := config.GenerateCalendarToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		feed := models.CalendarFeed{UserID: claims.UserID, TokenHash: hash}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("user_id = ?", claims.UserID).Delete(&models.CalendarFeed{}).Error; err != nil {
+				return err
+			}
+			return tx.Create(&feed).Error
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, CalendarFeedResponse{
+			Enabled:   true,
+			URL:       config.AppURL + "/calendar/" + token + ".ics",
+			CreatedAt: &feed.CreatedAt,
+		})
+	}
+}
+
+// DeleteCalendarFeed turns off the calendar feed of the user
+func DeleteCalendarFeed(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		if err := config.DeleteCalendarFeed(db, claims.UserID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Calendar feed has been turned off"})
+	}
+}
+
+// GetCalendarFeedEvents serves the iCalendar feed of the tasks with a due
+// date that the owner of the token owns or is assigned to. The token in the
+// URL is the only authentication, as calendar apps cannot log in.
+func GetCalendarFeedEvents(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var feed models.CalendarFeed
+		if err := db.Preload("User").Where("token_hash = ?", config.HashCalendarToken(mux.Vars(r)["token"])).First(&feed).Error; err != nil {
+			http.Error(w, "Calendar not found", http.StatusNotFound)
+			return
+		}
+		if config.AccountBlocked(feed.User) != nil {
+			http.Error(w, "Calendar not found", http.StatusNotFound)
+			return
+		}
+
+		var tasks []models.Task
+		if err := db.Where("(user_id = ? OR assignee_id = ?) AND due_date IS NOT NULL", feed.UserID, feed.UserID).Order("due_date, id").Find(&tasks).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		categories, err := categoryNames(db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		completed, err := completionTimes(db, tasks)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		events := make([]calendar.Event, 0, len(tasks))
+		for _, task := range tasks {
+			event := calendar.Event{Task: task, Category: categories[task.CategoryID]}
+			if completedAt, ok := completed[task.ID]; ok {
+				event.CompletedAt = &completedAt
+			}
+			events = append(events, event)
+		}
+
+		now := time.Now()
+		db.Model(&feed).UpdateColumn("last_fetched_at", now)
+
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		w.Header().Set("Content-Disposition", `inline; filename="tasks.ics"`)
+		w.Header().Set("Cache-Control", "no-cache, private")
+		if err := calendar.WriteFeed(w, "Tasks of "+feed.User.FullName, config.AppURL, events, now); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+// completionTimes finds when each done task was last marked as done
+func completionTimes(db *gorm.DB, tasks []models.Task) (map[uint]time.Time, error) {
+	var doneIDs []uint
+	for _, task := range tasks {
+		if task.Status {
+			doneIDs = append(doneIDs, task.ID)
+		}
+	}
+	times := map[uint]time.Time{}
+	if len(doneIDs) == 0 {
+		return times, nil
+	}
+
+	var transitions []models.TaskTransition
+	if err := db.Where("task_id IN ? AND status = ? AND (from_status IS NULL OR from_status = ?)", doneIDs, true, false).Order("created_at").Find(&transitions).Error; err != nil {
+		return nil, err
+	}
+	for _, transition := range transitions {
+		times[transition.TaskID] = transition.CreatedAt
+	}
+	return times, nil
+}
+
+func categoryNames(db *gorm.DB) (map[uint]string, error) {
+	var categories []models.Category
+	if err := db.Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	names := map[uint]string{}
+	for _, category := range categories {
+		names[category.ID] = category.Type
+	}
+	return names, nil
+}
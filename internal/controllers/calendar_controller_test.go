@@ -0,0 +1,81 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+)
+
+// createCalendarFeed turns on the calendar feed of the user and returns its
+// path
+func createCalendarFeed(t *testing.T, app *testApp, token string) string {
+	t.Helper()
+
+	rec := app.do(http.MethodPost, "/users/calendar-feed", nil, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create calendar feed returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimPrefix(body.URL, config.AppURL)
+}
+
+func TestCalendarFeedCompletesDoneTasks(t *testing.T) {
+	app := newTestApp(t)
+	category := app.createCategory("Todo")
+	user := app.createUser("member@example.com", policy.RoleMember)
+	feed := createCalendarFeed(t, app, app.tokenFor(user))
+
+	due := time.Date(2030, 1, 2, 9, 0, 0, 0, time.UTC)
+	done := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	open := models.Task{Title: "Open task", CategoryID: category.ID, UserID: user.ID, DueDate: &due}
+	finished := models.Task{Title: "Done task", CategoryID: category.ID, UserID: user.ID, DueDate: &due, Status: true}
+	app.db.Create(&open)
+	app.db.Create(&finished)
+	from := false
+	app.db.Create(&models.TaskTransition{TaskID: finished.ID, Kind: models.TransitionStatus, CategoryID: category.ID, FromStatus: &from, Status: true, CreatedAt: done})
+
+	rec := app.do(http.MethodGet, feed, nil, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("calendar feed returned %d: %s", rec.Code, rec.Body.String())
+	}
+	todos := strings.Split(rec.Body.String(), "BEGIN:VTODO")
+	if len(todos) != 3 {
+		t.Fatalf("feed has %d to-dos, want 2:\n%s", len(todos)-1, rec.Body.String())
+	}
+	if !strings.Contains(todos[1], "SUMMARY:Open task") || !strings.Contains(todos[1], "STATUS:NEEDS-ACTION") {
+		t.Errorf("open task is not a to-do that needs action:\n%s", todos[1])
+	}
+	if !strings.Contains(todos[2], "STATUS:COMPLETED") || !strings.Contains(todos[2], "COMPLETED:20300101T120000Z") {
+		t.Errorf("done task is not completed when it was done:\n%s", todos[2])
+	}
+}
+
+func TestPasswordResetTurnsOffCalendarFeed(t *testing.T) {
+	app := newTestApp(t)
+	user := app.createUser("member@example.com", policy.RoleMember)
+	feed := createCalendarFeed(t, app, app.tokenFor(user))
+
+	resetToken, err := config.IssueUserToken(app.db, user, models.TokenPurposeResetPassword, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := app.do(http.MethodPost, "/users/reset-password", map[string]string{"token": resetToken, "password": "Another-password-1"}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reset password returned %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := app.do(http.MethodGet, feed, nil, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("calendar feed after a password reset returned %d, want 404", rec.Code)
+	}
+}
@@ -7,8 +7,10 @@ import (
 
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CreateCategoryResponse struct {
@@ -21,7 +23,7 @@ type CreateCategoryResponse struct {
 func CreateCategory(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Autentikasi dan autorisasi
-		authorized, err := config.AuthenticateAndAuthorize(r, db)
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.CategoryManage)
 		if err != nil || !authorized {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -33,7 +35,8 @@ func CreateCategory(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		result := db.Create(&category)
+		// Only the category itself is saved, never nested tasks
+		result := db.Omit(clause.Associations).Create(&category)
 		if result.Error != nil {
 			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 			return
@@ -72,22 +75,15 @@ type TaskInfo struct {
 func GetCategories(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Authenticate the user
-		claims, err := config.Authenticate(r, db)
+		claims, actor, err := config.AuthenticateActor(r, db)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		// Check if the user is an admin
-		var user models.User
-		if err := db.Where("email = ?", claims.Email).First(&user).Error; err != nil {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-
-		// Get categories with tasks based on user role
+		// Get categories with tasks based on user permissions
 		var categories []models.Category
-		if user.Role == "admin" {
+		if policy.Has(db, actor, policy.TaskReadAny) {
 			// Admin can view all tasks
 			result := db.Preload("Tasks").Find(&categories)
 			if result.Error != nil {
@@ -145,7 +141,7 @@ type UpdateCategoryResponse struct {
 func UpdateCategory(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Autentikasi dan autorisasi
-		authorized, err := config.AuthenticateAndAuthorize(r, db)
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.CategoryManage)
 		if err != nil || !authorized {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -190,7 +186,7 @@ func UpdateCategory(db *gorm.DB) http.HandlerFunc {
 func DeleteCategory(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Autentikasi dan autorisasi
-		authorized, err := config.AuthenticateAndAuthorize(r, db)
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.CategoryManage)
 		if err != nil || !authorized {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/notifications"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type CommentResponse struct {
+	ID        uint      `json:"id"`
+	TaskID    uint      `json:"task_id"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+	User      struct {
+		ID       uint   `json:"id"`
+		Email    string `json:"email"`
+		FullName string `json:"full_name"`
+	} `json:"User"`
+}
+
+func newCommentResponse(comment models.Comment) CommentResponse {
+	response := CommentResponse{
+		ID:        comment.ID,
+		TaskID:    comment.TaskID,
+		Body:      comment.Body,
+		CreatedAt: comment.CreatedAt,
+	}
+	response.User.ID = comment.User.ID
+	response.User.Email = comment.User.Email
+	response.User.FullName = comment.User.FullName
+	return response
+}
+
+// CreateComment adds a comment to a task
+func CreateComment(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		taskID, err := strconv.Atoi(vars["taskId"])
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		var requestBody struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(requestBody.Body) == "" {
+			http.Error(w, "Comment body is required", http.StatusBadRequest)
+			return
+		}
+
+		var task models.Task
+		if err := db.First(&task, taskID).Error; err != nil {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+
+		if !policy.Can(db, actor, policy.CommentCreate, task) {
+			http.Error(w, "Unauthorized to comment on this task", http.StatusUnauthorized)
+			return
+		}
+
+		user := actor.User
+		comment := models.Comment{
+			TaskID: task.ID,
+			UserID: user.ID,
+			Body:   requestBody.Body,
+		}
+		if err := db.Create(&comment).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		comment.User = user
+
+		notifications.TaskCommented(db, task, comment, user)
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, newCommentResponse(comment))
+	}
+}
+
+// GetComments lists the comments of a task, oldest first
+func GetComments(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		taskID, err := strconv.Atoi(vars["taskId"])
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+
+		var task models.Task
+		if err := db.First(&task, taskID).Error; err != nil {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+
+		if !policy.Can(db, actor, policy.TaskRead, task) {
+			http.Error(w, "Unauthorized to view comments of this task", http.StatusUnauthorized)
+			return
+		}
+
+		var comments []models.Comment
+		if err := db.Where("task_id = ?", task.ID).Preload("User").Order("created_at").Find(&comments).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := []CommentResponse{}
+		for _, comment := range comments {
+			response = append(response, newCommentResponse(comment))
+		}
+
+		config.SendJSONResponse(w, response)
+	}
+}
@@ -0,0 +1,141 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/routes"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMain(m *testing.M) {
+	if err := config.LoadSigningKeys(); err != nil {
+		log.Fatalf("Error loading JWT keys: %v", err)
+	}
+
+	mockProvider := startMockProvider()
+	os.Setenv("OIDC_ISSUER", mockProvider.URL)
+	os.Setenv("OIDC_CLIENT_ID", mockClientID)
+
+	code := m.Run()
+	mockProvider.Close()
+	os.Exit(code)
+}
+
+// testApp is the API backed by a fresh database
+type testApp struct {
+	t      *testing.T
+	db     *gorm.DB
+	router *mux.Router
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_busy_timeout=5000&_journal_mode=WAL"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := config.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	router := mux.NewRouter()
+	routes.RegisterRoutes(router, db)
+	return &testApp{t: t, db: db, router: router}
+}
+
+// createUser adds a user with the given role and password "password"
+func (a *testApp) createUser(email, role string) models.User {
+	a.t.Helper()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	now := time.Now()
+	user := models.User{
+		FullName:        email,
+		Email:           email,
+		Password:        string(hashedPassword),
+		Role:            role,
+		EmailVerifiedAt: &now,
+	}
+	if err := a.db.Create(&user).Error; err != nil {
+		a.t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func (a *testApp) createCategory(name string) models.Category {
+	a.t.Helper()
+
+	category := models.Category{Type: name}
+	if err := a.db.Create(&category).Error; err != nil {
+		a.t.Fatalf("create category: %v", err)
+	}
+	return category
+}
+
+// tokenFor starts a session for the user and returns its bearer token
+func (a *testApp) tokenFor(user models.User) string {
+	a.t.Helper()
+
+	expiresAt := time.Now().Add(time.Hour)
+	session, err := config.CreateSession(a.db, user.ID, expiresAt)
+	if err != nil {
+		a.t.Fatalf("create session: %v", err)
+	}
+	token, err := config.SignToken(&config.Claims{
+		UserID:           user.ID,
+		Email:            user.Email,
+		RegisteredClaims: config.NewRegisteredClaims(session.ID, expiresAt),
+	})
+	if err != nil {
+		a.t.Fatalf("sign token: %v", err)
+	}
+	return token
+}
+
+// do sends a request with an optional JSON body and bearer token
+func (a *testApp) do(method, target string, body interface{}, token string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+	a.t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			a.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+	return rec
+}
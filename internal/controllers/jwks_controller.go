@@ -0,0 +1,16 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+)
+
+// GetJWKS publishes the public keys our tokens can be verified with
+func GetJWKS() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		config.SendJSONResponse(w, config.JWKS())
+	}
+}
@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"gorm.io/gorm"
+)
+
+// GetLabels lists the labels tasks can be tagged with
+func GetLabels(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := config.Authenticate(r, db); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		labels := []models.Label{}
+		if err := db.Order("name").Find(&labels).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, labels)
+	}
+}
+
+// CreateLabel creates a new label. Labels are shared like categories.
+func CreateLabel(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.CategoryManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var label models.Label
+		if err := json.NewDecoder(r.Body).Decode(&label); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		label.ID = 0
+		label.Name = strings.TrimSpace(label.Name)
+		if label.Name == "" {
+			http.Error(w, "Label name is required", http.StatusBadRequest)
+			return
+		}
+
+		if err := db.Create(&label).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, label)
+	}
+}
+
+// findLabels loads existing labels by ID. Tasks and templates can only use
+// labels that exist, creating them needs category.manage.
+func findLabels(w http.ResponseWriter, db *gorm.DB, labelIDs []uint) ([]models.Label, bool) {
+	labels := []models.Label{}
+	if len(labelIDs) == 0 {
+		return labels, true
+	}
+
+	if err := db.Where("id IN ?", labelIDs).Find(&labels).Error; err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	seen := map[uint]bool{}
+	for _, labelID := range labelIDs {
+		seen[labelID] = true
+	}
+	if len(labels) != len(seen) {
+		http.Error(w, "Label not found", http.StatusBadRequest)
+		return nil, false
+	}
+	return labels, true
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// After this many failures for one email, every further try has to wait
+	// twice as long as the previous one
+	loginDelayAfter = 3
+	maxLoginDelay   = time.Minute
+	// After this many failures for one email it is locked for loginLockout
+	loginLockAfter = 5
+	loginLockout   = 15 * time.Minute
+	// One address may fail this many times in loginIPWindow
+	maxIPFailures = 20
+	loginIPWindow = 15 * time.Minute
+)
+
+// invalidCredentials is the same for unknown emails and wrong passwords, so
+// the response does not tell which emails are registered
+const invalidCredentials = "Invalid email or password"
+
+// dummyPasswordHash is compared against when the email is unknown, so both
+// cases take as long
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+
+// loginRetryAfter returns how long the email or address has to wait before
+// the next login attempt, or zero when it may try now
+func loginRetryAfter(db *gorm.DB, email, ip string) time.Duration {
+	now := time.Now()
+
+	var ipFailures int64
+	db.Model(&models.LoginAttempt{}).
+		Where("ip = ? AND result = ? AND created_at > ?", ip, models.LoginFailed, now.Add(-loginIPWindow)).
+		Count(&ipFailures)
+	if ipFailures >= maxIPFailures {
+		return loginIPWindow
+	}
+
+	// Failures only count since the last success or unlock
+	since := now.Add(-loginLockout)
+	var reset models.LoginAttempt
+	if err := db.Where("email = ? AND result IN ?", email, []string{models.LoginSucceeded, models.LoginUnlocked}).
+		Order("created_at DESC").First(&reset).Error; err == nil && reset.CreatedAt.After(since) {
+		since = reset.CreatedAt
+	}
+
+	var failures []models.LoginAttempt
+	db.Where("email = ? AND result = ? AND created_at > ?", email, models.LoginFailed, since).
+		Order("created_at DESC").Limit(loginLockAfter).Find(&failures)
+	if len(failures) < loginDelayAfter {
+		return 0
+	}
+
+	last := failures[0].CreatedAt
+	wait := time.Duration(math.Pow(2, float64(len(failures)-loginDelayAfter))) * time.Second
+	if wait > maxLoginDelay {
+		wait = maxLoginDelay
+	}
+	if len(failures) >= loginLockAfter {
+		wait = loginLockout
+	}
+
+	return last.Add(wait).Sub(now)
+}
+
+func recordLoginAttempt(db *gorm.DB, email string, user *models.User, ip, result, reason string) {
+	attempt := models.LoginAttempt{
+		Email:  email,
+		IP:     ip,
+		Result: result,
+		Reason: reason,
+	}
+	if user != nil {
+		attempt.UserID = &user.ID
+	}
+	db.Create(&attempt)
+}
+
+// beginLoginAttempt checks the lockout and records the attempt in one
+// transaction, so parallel requests cannot all pass the check before the
+// first failure is written. The attempt counts as failed until it is
+// finished or cancelled. A non-zero wait means the attempt was refused.
+func beginLoginAttempt(db *gorm.DB, email, ip string) (models.LoginAttempt, time.Duration, error) {
+	var attempt models.LoginAttempt
+	var wait time.Duration
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := lockLoginEmail(tx, email); err != nil {
+			return err
+		}
+		if wait = loginRetryAfter(tx, email, ip); wait > 0 {
+			return nil
+		}
+		attempt = models.LoginAttempt{
+			Email:  email,
+			IP:     ip,
+			Result: models.LoginFailed,
+			Reason: "in progress",
+		}
+		return tx.Create(&attempt).Error
+	})
+	return attempt, wait, err
+}
+
+// lockLoginEmail makes attempts for the same email wait for each other
+// until the transaction ends. SQLite only runs one writer at a time anyway.
+func lockLoginEmail(tx *gorm.DB, email string) error {
+	if tx.Dialector.Name() != "postgres" {
+		return nil
+	}
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", email).Error
+}
+
+// finishLoginAttempt records the outcome of an attempt started with
+// beginLoginAttempt
+func finishLoginAttempt(db *gorm.DB, attempt *models.LoginAttempt, user *models.User, result, reason string) {
+	updates := map[string]interface{}{
+		"result": result,
+		"reason": reason,
+	}
+	if user != nil {
+		updates["user_id"] = user.ID
+	}
+	db.Model(attempt).Updates(updates)
+}
+
+// cancelLoginAttempt removes an attempt that neither failed nor finished a
+// login, such as a correct password that still needs the second factor
+func cancelLoginAttempt(db *gorm.DB, attempt *models.LoginAttempt) {
+	db.Delete(attempt)
+}
+
+func sendTooManyAttempts(w http.ResponseWriter, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	w.Header().Set("Retry-After", fmt.Sprint(seconds))
+	http.Error(w, fmt.Sprintf("Too many failed login attempts, try again in %d seconds", seconds), http.StatusTooManyRequests)
+}
@@ -0,0 +1,85 @@
+package controllers_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+)
+
+func TestLoginNormalizesEmail(t *testing.T) {
+	app := newTestApp(t)
+	app.createUser("Mixed@Example.com", "member")
+
+	rec := app.do(http.MethodPost, "/users/login", map[string]string{"email": " mixed@example.COM ", "password": "password"}, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login returned %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestEmailsAreUniqueRegardlessOfCase(t *testing.T) {
+	app := newTestApp(t)
+
+	rec := app.do(http.MethodPost, "/users/register", map[string]string{"full_name": "First", "email": " First@Example.com ", "password": "A-long-password-1"}, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register returned %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = app.do(http.MethodPost, "/users/register", map[string]string{"full_name": "Second", "email": "first@example.com", "password": "A-long-password-1"}, "")
+	if rec.Code == http.StatusCreated {
+		t.Fatal("the same email in other case was registered twice")
+	}
+
+	var user models.User
+	if err := app.db.Where("email = ?", "first@example.com").First(&user).Error; err != nil {
+		t.Fatalf("email was not stored normalized: %v", err)
+	}
+	if err := app.db.Create(&models.User{FullName: "Third", Email: "FIRST@example.com", Password: "x", Role: "member"}).Error; err == nil {
+		t.Error("the database accepted the same email in other case")
+	}
+}
+
+func TestPasswordCheckFailuresCountTowardsLockout(t *testing.T) {
+	app := newTestApp(t)
+	user := app.createUser("locked@example.com", "member")
+	token := app.tokenFor(user)
+
+	for i := 0; i < 3; i++ {
+		rec := app.do(http.MethodPut, "/users/change-password", map[string]string{"current_password": "wrong", "new_password": "Another-password-1"}, token)
+		if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("change password returned %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+	for i := 0; i < 2; i++ {
+		rec := app.do(http.MethodPut, "/users/update-account", map[string]string{"email": "new@example.com", "current_password": "wrong"}, token)
+		if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusTooManyRequests {
+			t.Fatalf("update account returned %d: %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := app.do(http.MethodPost, "/users/login", map[string]string{"email": "locked@example.com", "password": "password"}, "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("login after failed password checks returned %d, want 429", rec.Code)
+	}
+}
+
+func TestParallelLoginsCannotPassTheLockout(t *testing.T) {
+	app := newTestApp(t)
+	app.createUser("parallel@example.com", "member")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			app.do(http.MethodPost, "/users/login", map[string]string{"email": "parallel@example.com", "password": "wrong"}, "")
+		}()
+	}
+	wg.Wait()
+
+	var checked int64
+	app.db.Model(&models.LoginAttempt{}).Where("email = ?", "parallel@example.com").Count(&checked)
+	if checked > 5 {
+		t.Fatalf("%d passwords were checked, the lockout allows 5", checked)
+	}
+}
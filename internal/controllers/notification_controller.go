@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/notifications"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type GetNotificationsResponse struct {
+	Notifications []models.Notification `json:"notifications"`
+	Page          int                   `json:"page"`
+	Limit         int                   `json:"limit"`
+	Total         int64                 `json:"total"`
+	UnreadCount   int64                 `json:"unread_count"`
+}
+
+func countUnreadNotifications(db *gorm.DB, userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// GetNotifications lists the inbox of the current user, newest first. Pass
+// unread=true to only list unread notifications.
+func GetNotifications(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		page, limit := parsePagination(r)
+
+		query := db.Model(&models.Notification{}).Where("user_id = ?", claims.UserID)
+		if r.URL.Query().Get("unread") == "true" {
+			query = query.Where("read_at IS NULL")
+		}
+		query = query.Session(&gorm.Session{})
+
+		var total int64
+		if err := query.Count(&total).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		inbox := []models.Notification{}
+		if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&inbox).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		unreadCount, err := countUnreadNotifications(db, claims.UserID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, GetNotificationsResponse{
+			Notifications: inbox,
+			Page:          page,
+			Limit:         limit,
+			Total:         total,
+			UnreadCount:   unreadCount,
+		})
+	}
+}
+
+// GetUnreadNotificationCount returns how many notifications are still unread
+func GetUnreadNotificationCount(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		unreadCount, err := countUnreadNotifications(db, claims.UserID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]int64{"unread_count": unreadCount})
+	}
+}
+
+// MarkNotificationRead marks a single notification of the current user as read
+func MarkNotificationRead(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		notificationID, err := strconv.Atoi(vars["notificationId"])
+		if err != nil {
+			http.Error(w, "Invalid notification ID", http.StatusBadRequest)
+			return
+		}
+
+		var notification models.Notification
+		if err := db.Where("id = ? AND user_id = ?", notificationID, claims.UserID).First(&notification).Error; err != nil {
+			http.Error(w, "Notification not found", http.StatusNotFound)
+			return
+		}
+
+		if notification.ReadAt == nil {
+			now := time.Now()
+			if err := db.Model(&notification).Update("read_at", now).Error; err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			notification.ReadAt = &now
+		}
+
+		config.SendJSONResponse(w, notification)
+	}
+}
+
+// MarkAllNotificationsRead marks every unread notification of the current user as read
+func MarkAllNotificationsRead(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		result := db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", claims.UserID).Update("read_at", time.Now())
+		if result.Error != nil {
+			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]interface{}{
+			"message": "All notifications have been marked as read",
+			"updated": result.RowsAffected,
+		})
+	}
+}
+
+// GetNotificationPreferences returns the email preferences of the current user
+func GetNotificationPreferences(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		config.SendJSONResponse(w, notifications.Preferences(db, claims.UserID))
+	}
+}
+
+// UpdateNotificationPreferences changes the email preferences of the current
+// user. Fields missing from the body keep their current value.
+func UpdateNotificationPreferences(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		pref := notifications.Preferences(db, claims.UserID)
+
+		var requestBody struct {
+			EmailOnAssign  *bool `json:"email_on_assign"`
+			EmailOnDueSoon *bool `json:"email_on_due_soon"`
+			EmailOnComment *bool `json:"email_on_comment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if requestBody.EmailOnAssign != nil {
+			pref.EmailOnAssign = *requestBody.EmailOnAssign
+		}
+		if requestBody.EmailOnDueSoon != nil {
+			pref.EmailOnDueSoon = *requestBody.EmailOnDueSoon
+		}
+		if requestBody.EmailOnComment != nil {
+			pref.EmailOnComment = *requestBody.EmailOnComment
+		}
+
+		if err := db.Save(&pref).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, pref)
+	}
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/oidc"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// oidcStateCookie holds the state of the login started in this browser
+const oidcStateCookie = "oidc_state"
+
+// OIDCLogin sends the user to the OpenID Connect provider to log in
+func OIDCLogin(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider, err := oidc.Default()
+		if err == oidc.ErrNotConfigured {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		state, err := oidc.RandomString()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		nonce, err := oidc.RandomString()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		verifier, challenge, err := oidc.NewPKCE()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		loginState := models.OIDCLoginState{
+			State:        state,
+			Nonce:        nonce,
+			CodeVerifier: verifier,
+			ExpiresAt:    time.Now().Add(10 * time.Minute),
+		}
+		if err := db.Create(&loginState).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Clean up logins that were never finished
+		db.Where("expires_at < ?", time.Now()).Delete(&models.OIDCLoginState{})
+
+		// Bind the state to this browser so a callback started elsewhere is refused
+		http.SetCookie(w, &http.Cookie{
+			Name:     oidcStateCookie,
+			Value:    state,
+			Path:     "/users/oidc",
+			Expires:  loginState.ExpiresAt,
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(provider.RedirectURL, "https://"),
+			SameSite: http.SameSiteLaxMode,
+		})
+
+		http.Redirect(w, r, provider.AuthCodeURL(state, nonce, challenge), http.StatusFound)
+	}
+}
+
+// OIDCCallback finishes a login with the OpenID Connect provider and hands
+// out our own token, just like LoginUser
+func OIDCCallback(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider, err := oidc.Default()
+		if err == oidc.ErrNotConfigured {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+
+		query := r.URL.Query()
+		if errorCode := query.Get("error"); errorCode != "" {
+			http.Error(w, "Login was cancelled or failed: "+errorCode, http.StatusUnauthorized)
+			return
+		}
+
+		// The state must come back to the browser that started the login
+		state := query.Get("state")
+		cookie, err := r.Cookie(oidcStateCookie)
+		http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Path: "/users/oidc", MaxAge: -1, HttpOnly: true})
+		if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+			http.Error(w, "Invalid or expired login state", http.StatusBadRequest)
+			return
+		}
+
+		// The state can be used once
+		now := time.Now()
+		var loginState models.OIDCLoginState
+		if err := db.Where("state = ? AND expires_at > ?", state, now).First(&loginState).Error; err != nil {
+			http.Error(w, "Invalid or expired login state", http.StatusBadRequest)
+			return
+		}
+		result := db.Where("state = ? AND expires_at > ?", state, now).Delete(&models.OIDCLoginState{})
+		if result.Error != nil || result.RowsAffected != 1 {
+			http.Error(w, "Invalid or expired login state", http.StatusBadRequest)
+			return
+		}
+
+		rawIDToken, err := provider.Exchange(query.Get("code"), loginState.CodeVerifier)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		idToken, err := provider.VerifyIDToken(rawIDToken, loginState.Nonce)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		user, err := userForIdentity(db, provider.Issuer, idToken)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		completeLogin(w, r, db, user, "oidc", nil)
+	}
+}
+
+// userForIdentity finds the user linked to an external identity. Unknown
+// identities are linked by verified email to an existing user, or a new
+// member is created for them.
+func userForIdentity(db *gorm.DB, issuer string, idToken *oidc.IDTokenClaims) (models.User, error) {
+	var user models.User
+
+	var identity models.UserIdentity
+	err := db.Where("issuer = ? AND subject = ?", issuer, idToken.Subject).First(&identity).Error
+	if err == nil {
+		err = db.First(&user, identity.UserID).Error
+		return user, err
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return user, err
+	}
+
+	email := config.NormalizeEmail(idToken.Email)
+	if email == "" || !idToken.EmailVerified {
+		return user, errors.New("The provider did not return a verified email address")
+	}
+	if err := config.ValidateEmail(email); err != nil {
+		return user, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("LOWER(email) = ?", email).First(&user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			user, err = provisionMember(tx, email, idToken.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		// The provider verified the address, so we can trust it too
+		if user.EmailVerifiedAt == nil {
+			now := time.Now()
+			if err := tx.Model(&user).Update("email_verified_at", now).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.UserIdentity{
+			UserID:  user.ID,
+			Issuer:  issuer,
+			Subject: idToken.Subject,
+			Email:   email,
+		}).Error
+	})
+	return user, err
+}
+
+// provisionMember creates a member for a first time external login. The
+// random password cannot be used, the member logs in through the provider
+// or resets it by email.
+func provisionMember(db *gorm.DB, email, fullName string) (models.User, error) {
+	password, err := oidc.RandomString()
+	if err != nil {
+		return models.User{}, err
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	if fullName == "" {
+		fullName = email
+	}
+
+	user := models.User{
+		FullName: fullName,
+		Email:    email,
+		Password: string(hashedPassword),
+		Role:     "member",
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return user, err
+	}
+
+	log.Printf("Created member %s from an OIDC login", email)
+	return user, nil
+}
@@ -0,0 +1,207 @@
+package controllers_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const mockClientID = "kanban"
+
+// mockProvider is a minimal OpenID Connect provider. Tests register the
+// ID token claims to hand out for an authorization code.
+var mockProvider struct {
+	mu     sync.Mutex
+	url    string
+	key    *rsa.PrivateKey
+	tokens map[string]jwt.MapClaims
+}
+
+func startMockProvider() *httptest.Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	mockProvider.key = key
+	mockProvider.tokens = make(map[string]jwt.MapClaims)
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	mockProvider.url = server.URL
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		encode := base64.RawURLEncoding.EncodeToString
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "test",
+				"use": "sig",
+				"n":   encode(key.N.Bytes()),
+				"e":   encode(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		mockProvider.mu.Lock()
+		claims, ok := mockProvider.tokens[r.FormValue("code")]
+		delete(mockProvider.tokens, r.FormValue("code"))
+		mockProvider.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+	return server
+}
+
+// issueCode makes the mock provider accept code and answer it with an ID
+// token for the given subject
+func issueCode(code, subject, email, nonce string) {
+	mockProvider.mu.Lock()
+	defer mockProvider.mu.Unlock()
+	mockProvider.tokens[code] = jwt.MapClaims{
+		"iss":            mockProvider.url,
+		"aud":            mockClientID,
+		"sub":            subject,
+		"email":          email,
+		"email_verified": true,
+		"nonce":          nonce,
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+	}
+}
+
+// startOIDCLogin begins a login and returns the state cookie and the
+// state and nonce sent to the provider
+func startOIDCLogin(t *testing.T, app *testApp) (*http.Cookie, string, string) {
+	t.Helper()
+
+	rec := app.do(http.MethodGet, "/users/oidc/login", nil, "")
+	if rec.Code != http.StatusFound {
+		t.Fatalf("login returned %d: %s", rec.Code, rec.Body.String())
+	}
+	location, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "oidc_state" {
+			cookie = c
+		}
+	}
+	if cookie == nil {
+		t.Fatal("login did not set the state cookie")
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+		t.Errorf("state cookie must be HttpOnly and SameSite=Lax, got %+v", cookie)
+	}
+	if cookie.Value != location.Query().Get("state") {
+		t.Errorf("state cookie %q does not match the state sent to the provider", cookie.Value)
+	}
+	return cookie, location.Query().Get("state"), location.Query().Get("nonce")
+}
+
+func callbackURL(code, state string) string {
+	return "/users/oidc/callback?" + url.Values{"code": {code}, "state": {state}}.Encode()
+}
+
+func TestOIDCCallbackLogsIn(t *testing.T) {
+	app := newTestApp(t)
+	cookie, state, nonce := startOIDCLogin(t, app)
+	issueCode("code-login", "subject-1", "oidc@example.com", nonce)
+
+	rec := app.do(http.MethodGet, callbackURL("code-login", state), nil, "", cookie)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("callback returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var body map[string]interface{}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body["token"] == nil {
+		t.Fatalf("callback did not return a token: %s", rec.Body.String())
+	}
+
+	var identity models.UserIdentity
+	if err := app.db.Where("subject = ?", "subject-1").First(&identity).Error; err != nil {
+		t.Fatalf("identity was not linked: %v", err)
+	}
+}
+
+func TestOIDCCallbackRequiresStateCookie(t *testing.T) {
+	app := newTestApp(t)
+	_, state, nonce := startOIDCLogin(t, app)
+	issueCode("code-no-cookie", "subject-2", "csrf@example.com", nonce)
+
+	rec := app.do(http.MethodGet, callbackURL("code-no-cookie", state), nil, "")
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("callback without cookie returned %d, want 400", rec.Code)
+	}
+}
+
+func TestOIDCCallbackRejectsStateOfAnotherBrowser(t *testing.T) {
+	app := newTestApp(t)
+	_, victimState, _ := startOIDCLogin(t, app)
+	attackerCookie, _, attackerNonce := startOIDCLogin(t, app)
+	issueCode("code-other", "subject-3", "attacker@example.com", attackerNonce)
+
+	rec := app.do(http.MethodGet, callbackURL("code-other", victimState), nil, "", attackerCookie)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("callback with another browser's state returned %d, want 400", rec.Code)
+	}
+}
+
+func TestOIDCCallbackStateIsSingleUse(t *testing.T) {
+	app := newTestApp(t)
+	cookie, state, nonce := startOIDCLogin(t, app)
+	issueCode("code-first", "subject-4", "once@example.com", nonce)
+	issueCode("code-second", "subject-4", "once@example.com", nonce)
+
+	if rec := app.do(http.MethodGet, callbackURL("code-first", state), nil, "", cookie); rec.Code != http.StatusOK {
+		t.Fatalf("first callback returned %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := app.do(http.MethodGet, callbackURL("code-second", state), nil, "", cookie); rec.Code != http.StatusBadRequest {
+		t.Fatalf("replayed callback returned %d, want 400", rec.Code)
+	}
+}
+
+func TestOIDCCallbackRejectsExpiredState(t *testing.T) {
+	app := newTestApp(t)
+	cookie, state, nonce := startOIDCLogin(t, app)
+	issueCode("code-expired", "subject-5", "late@example.com", nonce)
+	app.db.Model(&models.OIDCLoginState{}).Where("state = ?", state).Update("expires_at", time.Now().Add(-time.Minute))
+
+	rec := app.do(http.MethodGet, callbackURL("code-expired", state), nil, "", cookie)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("callback with expired state returned %d, want 400", rec.Code)
+	}
+}
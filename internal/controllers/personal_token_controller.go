@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type PersonalTokenResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func newPersonalTokenResponse(token models.PersonalAccessToken) PersonalTokenResponse {
+	return PersonalTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     strings.Fields(token.Scopes),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+// authenticateSession is Authenticate for routes that personal access tokens
+// must not reach, such as managing the tokens themselves
+func authenticateSession(w http.ResponseWriter, r *http.Request, db *gorm.DB) (*config.Claims, bool) {
+	claims, err := config.Authenticate(r, db)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+	if claims.PersonalTokenID != 0 {
+		http.Error(w, "This action requires logging in, personal access tokens cannot be used", http.StatusForbidden)
+		return nil, false
+	}
+	return claims, true
+}
+
+// CreatePersonalToken creates a personal access token. The token is only
+// shown in this response.
+func CreatePersonalToken(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		var requestBody struct {
+			Name          string   `json:"name"`
+			Scopes        []string `json:"scopes"`
+			ExpiresInDays int      `json:"expires_in_days"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if strings.TrimSpace(requestBody.Name) == "" {
+			http.Error(w, "Token name is required", http.StatusBadRequest)
+			return
+		}
+		if len(requestBody.Scopes) == 0 {
+			http.Error(w, "At least one scope is required", http.StatusBadRequest)
+			return
+		}
+		for _, scope := range requestBody.Scopes {
+			valid := false
+			for _, known := range config.PersonalTokenScopes {
+				valid = valid || scope == known
+			}
+			if !valid {
+				http.Error(w, "Unknown scope "+scope, http.StatusBadRequest)
+				return
+			}
+		}
+		if requestBody.ExpiresInDays < 0 {
+			http.Error(w, "expires_in_days cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		plainToken, hash, err := config.GeneratePersonalToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		token := models.PersonalAccessToken{
+			UserID:    claims.UserID,
+			Name:      requestBody.Name,
+			TokenHash: hash,
+			Prefix:    plainToken[:len(config.PersonalTokenPrefix)+6],
+			Scopes:    strings.Join(requestBody.Scopes, " "),
+		}
+		if requestBody.ExpiresInDays > 0 {
+			expiresAt := time.Now().AddDate(0, 0, requestBody.ExpiresInDays)
+			token.ExpiresAt = &expiresAt
+		}
+
+		if err := db.Create(&token).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := newPersonalTokenResponse(token)
+		response.Token = plainToken
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, response)
+	}
+}
+
+// GetPersonalTokens lists the active personal access tokens of the current user
+func GetPersonalTokens(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		var tokens []models.PersonalAccessToken
+		if err := db.Where("user_id = ? AND revoked_at IS NULL", claims.UserID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := []PersonalTokenResponse{}
+		for _, token := range tokens {
+			response = append(response, newPersonalTokenResponse(token))
+		}
+
+		config.SendJSONResponse(w, response)
+	}
+}
+
+// RevokePersonalToken revokes a personal access token of the current user
+func RevokePersonalToken(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		vars := mux.Vars(r)
+		tokenID, err := strconv.Atoi(vars["tokenId"])
+		if err != nil {
+			http.Error(w, "Invalid token ID", http.StatusBadRequest)
+			return
+		}
+
+		result := db.Model(&models.PersonalAccessToken{}).
+			Where("id = ? AND user_id = ? AND revoked_at IS NULL", tokenID, claims.UserID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+			return
+		}
+		if result.RowsAffected == 0 {
+			http.Error(w, "Token not found", http.StatusNotFound)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Token has been successfully revoked"})
+	}
+}
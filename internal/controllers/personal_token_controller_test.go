@@ -0,0 +1,62 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+)
+
+// createPersonalToken creates a personal access token with the scopes
+func createPersonalToken(t *testing.T, app *testApp, session string, scopes ...string) string {
+	t.Helper()
+
+	rec := app.do(http.MethodPost, "/users/tokens", map[string]interface{}{"name": "script", "scopes": scopes}, session)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create personal token returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	return body.Token
+}
+
+func TestPersonalTokensCannotChangeTheEmail(t *testing.T) {
+	app := newTestApp(t)
+	user := app.createUser("owner@example.com", "member")
+	token := createPersonalToken(t, app, app.tokenFor(user), "write")
+
+	rec := app.do(http.MethodPut, "/users/update-account", map[string]string{"email": "attacker@example.com", "current_password": "password"}, token)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("update account with a personal token returned %d, want 403", rec.Code)
+	}
+}
+
+func TestEmailChangeNeedsTheCurrentPassword(t *testing.T) {
+	app := newTestApp(t)
+	user := app.createUser("owner@example.com", "member")
+	session := app.tokenFor(user)
+
+	rec := app.do(http.MethodPut, "/users/update-account", map[string]string{"email": "new@example.com", "current_password": "wrong"}, session)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("email change with a wrong password returned %d, want 401", rec.Code)
+	}
+	rec = app.do(http.MethodPut, "/users/update-account", map[string]string{"full_name": "Renamed"}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("name change without a password returned %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = app.do(http.MethodPut, "/users/update-account", map[string]string{"email": "new@example.com", "current_password": "password"}, session)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("email change with the password returned %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var reloaded models.User
+	app.db.First(&reloaded, user.ID)
+	if reloaded.Email != "new@example.com" || reloaded.FullName != "Renamed" {
+		t.Errorf("account is %q <%s>", reloaded.FullName, reloaded.Email)
+	}
+}
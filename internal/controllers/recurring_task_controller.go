@@ -0,0 +1,275 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/recurrence"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type recurringTaskRequest struct {
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	CategoryID   uint       `json:"category_id"`
+	AssigneeID   *uint      `json:"assignee_id"`
+	Rule         string     `json:"rule"`
+	StartAt      *time.Time `json:"start_at"`
+	OnCompletion bool       `json:"on_completion"`
+}
+
+// decodeRecurringTask reads a series and checks its category, assignee and
+// rule
+func decodeRecurringTask(w http.ResponseWriter, r *http.Request, db *gorm.DB, series *models.RecurringTask) bool {
+	var requestBody recurringTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	requestBody.Title = strings.TrimSpace(requestBody.Title)
+	if requestBody.Title == "" {
+		http.Error(w, "Title is required", http.StatusBadRequest)
+		return false
+	}
+
+	var category models.Category
+	if err := db.First(&category, requestBody.CategoryID).Error; err != nil {
+		http.Error(w, "Category not found", http.StatusBadRequest)
+		return false
+	}
+	if requestBody.AssigneeID != nil {
+		var assignee models.User
+		if err := db.First(&assignee, *requestBody.AssigneeID).Error; err != nil || config.AccountBlocked(assignee) != nil {
+			http.Error(w, "Assignee not found", http.StatusBadRequest)
+			return false
+		}
+	}
+
+	rule, err := recurrence.Parse(requestBody.Rule)
+	if err != nil {
+		http.Error(w, "Invalid rule: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	series.Title = requestBody.Title
+	series.Description = requestBody.Description
+	series.CategoryID = requestBody.CategoryID
+	series.AssigneeID = requestBody.AssigneeID
+	series.Rule = rule.String()
+	series.OnCompletion = requestBody.OnCompletion
+	if requestBody.StartAt != nil {
+		series.StartAt = *requestBody.StartAt
+	} else if series.StartAt.IsZero() {
+		series.StartAt = time.Now()
+	}
+	return true
+}
+
+// findRecurringTask loads a series the user owns, or any series for users
+// who can update any task
+func findRecurringTask(w http.ResponseWriter, r *http.Request, db *gorm.DB, actor policy.Actor) (models.RecurringTask, bool) {
+	var series models.RecurringTask
+	if err := db.First(&series, mux.Vars(r)["recurringTaskId"]).Error; err != nil {
+		http.Error(w, "Recurring task not found", http.StatusNotFound)
+		return series, false
+	}
+	if series.UserID != actor.User.ID && !policy.Has(db, actor, policy.TaskUpdateAny) {
+		http.Error(w, "Recurring task not found", http.StatusNotFound)
+		return series, false
+	}
+	return series, true
+}
+
+// GetRecurringTasks lists the recurring tasks of the user
+func GetRecurringTasks(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		series := []models.RecurringTask{}
+		if err := db.Where("user_id = ?", claims.UserID).Order("id").Find(&series).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, series)
+	}
+}
+
+// GetRecurringTask returns a recurring task
+func GetRecurringTask(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		series, ok := findRecurringTask(w, r, db, actor)
+		if !ok {
+			return
+		}
+
+		config.SendJSONResponse(w, series)
+	}
+}
+
+// CreateRecurringTask starts a series of tasks created following a rule
+func CreateRecurringTask(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		if !policy.Has(db, actor, policy.TaskCreate) {
+			http.Error(w, "Unauthorized to create tasks", http.StatusUnauthorized)
+			return
+		}
+
+		// A series starting now creates its first task right away
+		now := time.Now()
+		series := models.RecurringTask{UserID: claims.UserID}
+		if !decodeRecurringTask(w, r, db, &series) {
+			return
+		}
+		if err := recurrence.Schedule(db, &series, now); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := db.Create(&series).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, series)
+	}
+}
+
+// UpdateRecurringTask edits a series. Tasks already created are left as
+// they are.
+func UpdateRecurringTask(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		series, ok := findRecurringTask(w, r, db, actor)
+		if !ok {
+			return
+		}
+		if !decodeRecurringTask(w, r, db, &series) {
+			return
+		}
+		if err := recurrence.Schedule(db, &series, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Only the editable columns are written, so a task created by the
+		// scheduler meanwhile keeps its occurrences and last task
+		if err := db.Model(&series).Select("title", "description", "category_id", "assignee_id", "rule", "start_at", "on_completion", "next_at").Updates(&series).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, series)
+	}
+}
+
+// PauseRecurringTask stops a series from creating tasks
+func PauseRecurringTask(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		series, ok := findRecurringTask(w, r, db, actor)
+		if !ok {
+			return
+		}
+
+		series.Paused = true
+		if err := db.Model(&series).Update("paused", true).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, series)
+	}
+}
+
+// ResumeRecurringTask starts a paused series again from its next
+// occurrence. Occurrences missed while paused are skipped.
+func ResumeRecurringTask(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		series, ok := findRecurringTask(w, r, db, actor)
+		if !ok {
+			return
+		}
+
+		series.Paused = false
+		if err := recurrence.Schedule(db, &series, time.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := db.Model(&series).Select("paused", "next_at").Updates(&series).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, series)
+	}
+}
+
+// DeleteRecurringTask ends a series. The tasks it created are kept.
+func DeleteRecurringTask(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		series, ok := findRecurringTask(w, r, db, actor)
+		if !ok {
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Task{}).Where("recurring_task_id = ?", series.ID).Update("recurring_task_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&series).Error
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Recurring task has been successfully deleted"})
+	}
+}
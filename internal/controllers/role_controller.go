@@ -0,0 +1,342 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type RoleResponse struct {
+	ID          uint                `json:"id,omitempty"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Builtin     bool                `json:"builtin"`
+	Permissions []policy.Permission `json:"permissions"`
+}
+
+type roleRequest struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Permissions []policy.Permission `json:"permissions"`
+}
+
+func (req roleRequest) validate() error {
+	if strings.TrimSpace(req.Name) == "" {
+		return errors.New("Role name is required")
+	}
+	if _, builtin := policy.BuiltinRoles[req.Name]; builtin {
+		return errors.New("Role name is reserved")
+	}
+	for _, permission := range req.Permissions {
+		if !policy.IsValid(permission) {
+			return errors.New("Unknown permission " + string(permission))
+		}
+	}
+	return nil
+}
+
+func saveRolePermissions(tx *gorm.DB, roleID uint, permissions []policy.Permission) error {
+	if err := tx.Where("role_id = ?", roleID).Delete(&models.RolePermission{}).Error; err != nil {
+		return err
+	}
+	seen := map[policy.Permission]bool{}
+	for _, permission := range permissions {
+		if seen[permission] {
+			continue
+		}
+		seen[permission] = true
+		if err := tx.Create(&models.RolePermission{RoleID: roleID, Permission: string(permission)}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPermissions lists every permission a role can be given
+func GetPermissions(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.RoleManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		config.SendJSONResponse(w, policy.All)
+	}
+}
+
+// GetRoles lists the built-in and custom roles with their permissions
+func GetRoles(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.RoleManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		response := []RoleResponse{
+			{Name: policy.RoleAdmin, Description: "Full access", Builtin: true, Permissions: policy.BuiltinRoles[policy.RoleAdmin]},
+			{Name: policy.RoleMember, Description: "Manages their own tasks", Builtin: true, Permissions: policy.BuiltinRoles[policy.RoleMember]},
+		}
+
+		var roles []models.Role
+		if err := db.Order("name").Find(&roles).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, role := range roles {
+			response = append(response, RoleResponse{
+				ID:          role.ID,
+				Name:        role.Name,
+				Description: role.Description,
+				Permissions: policy.Permissions(db, role.Name),
+			})
+		}
+
+		config.SendJSONResponse(w, response)
+	}
+}
+
+// CreateRole creates a custom role
+func CreateRole(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !policy.Has(db, actor, policy.RoleManage) {
+			http.Error(w, "Unauthorized access", http.StatusUnauthorized)
+			return
+		}
+
+		var requestBody roleRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := requestBody.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !policy.HasAll(db, actor, requestBody.Permissions) {
+			http.Error(w, "Cannot grant permissions you do not have", http.StatusForbidden)
+			return
+		}
+
+		role := models.Role{Name: requestBody.Name, Description: requestBody.Description}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+			return saveRolePermissions(tx, role.ID, requestBody.Permissions)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, RoleResponse{
+			ID:          role.ID,
+			Name:        role.Name,
+			Description: role.Description,
+			Permissions: policy.Permissions(db, role.Name),
+		})
+	}
+}
+
+// UpdateRole replaces the name, description and permissions of a custom role.
+// The role of the last admins keeps every permission.
+func UpdateRole(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !policy.Has(db, actor, policy.RoleManage) {
+			http.Error(w, "Unauthorized access", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		roleID, err := strconv.Atoi(vars["roleId"])
+		if err != nil {
+			http.Error(w, "Invalid role ID", http.StatusBadRequest)
+			return
+		}
+
+		var requestBody roleRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := requestBody.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var role models.Role
+		if err := db.First(&role, roleID).Error; err != nil {
+			http.Error(w, "Role not found", http.StatusNotFound)
+			return
+		}
+		if !policy.Covers(db, actor, role.Name) || !policy.HasAll(db, actor, requestBody.Permissions) {
+			http.Error(w, "Cannot grant permissions you do not have", http.StatusForbidden)
+			return
+		}
+		if !policy.IsFull(requestBody.Permissions) && isLastAdminRole(db, role.Name) {
+			http.Error(w, "Cannot take permissions from the role of the last admins", http.StatusConflict)
+			return
+		}
+
+		oldName := role.Name
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&role).Updates(map[string]interface{}{
+				"name":        requestBody.Name,
+				"description": requestBody.Description,
+			}).Error; err != nil {
+				return err
+			}
+			// Users refer to their role by name
+			if oldName != requestBody.Name {
+				if err := tx.Model(&models.User{}).Where("role = ?", oldName).Update("role", requestBody.Name).Error; err != nil {
+					return err
+				}
+			}
+			return saveRolePermissions(tx, role.ID, requestBody.Permissions)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, RoleResponse{
+			ID:          role.ID,
+			Name:        requestBody.Name,
+			Description: requestBody.Description,
+			Permissions: policy.Permissions(db, requestBody.Name),
+		})
+	}
+}
+
+// DeleteRole deletes a custom role that no user has, unless it is the role
+// of the last admins
+func DeleteRole(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.RoleManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		roleID, err := strconv.Atoi(vars["roleId"])
+		if err != nil {
+			http.Error(w, "Invalid role ID", http.StatusBadRequest)
+			return
+		}
+
+		var role models.Role
+		if err := db.First(&role, roleID).Error; err != nil {
+			http.Error(w, "Role not found", http.StatusNotFound)
+			return
+		}
+
+		if isLastAdminRole(db, role.Name) {
+			http.Error(w, "Cannot delete the role of the last admins", http.StatusConflict)
+			return
+		}
+
+		var userCount int64
+		if err := db.Model(&models.User{}).Where("role = ?", role.Name).Count(&userCount).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if userCount > 0 {
+			http.Error(w, "Cannot delete a role that users still have", http.StatusConflict)
+			return
+		}
+
+		if err := db.Select("Permissions").Delete(&role).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Role has been successfully deleted"})
+	}
+}
+
+// UpdateUserRole gives a user another role. The actor needs every permission
+// of both the old and the new role, and the last admin cannot be demoted.
+func UpdateUserRole(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !policy.Has(db, actor, policy.UserManage) {
+			http.Error(w, "Unauthorized access", http.StatusUnauthorized)
+			return
+		}
+
+		vars := mux.Vars(r)
+		userID, err := strconv.Atoi(vars["userId"])
+		if err != nil {
+			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var requestBody struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !policy.RoleExists(db, requestBody.Role) {
+			http.Error(w, "Role not found", http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, userID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		// Nobody can give or take away more than they have themselves
+		if !policy.Covers(db, actor, requestBody.Role) || !policy.Covers(db, actor, user.Role) {
+			http.Error(w, "Cannot manage users with permissions you do not have", http.StatusForbidden)
+			return
+		}
+
+		if !policy.Grants(db, requestBody.Role, policy.All) && isLastAdmin(db, user) {
+			http.Error(w, "Cannot demote the last admin", http.StatusConflict)
+			return
+		}
+
+		if err := db.Model(&user).Update("role", requestBody.Role).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]interface{}{
+			"id":         user.ID,
+			"full_name":  user.FullName,
+			"email":      user.Email,
+			"role":       user.Role,
+			"updated_at": user.UpdatedAt,
+		})
+	}
+}
@@ -0,0 +1,260 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/gorilla/mux"
+)
+
+// publicRoutes can be used without logging in
+var publicRoutes = map[string]bool{
+	"GET /":                           true,
+	"POST /setup":                     true,
+	"GET /.well-known/jwks.json":      true,
+	"POST /users/register":            true,
+	"POST /users/login":               true,
+	"POST /users/login/2fa":           true,
+	"GET /users/oidc/login":           true,
+	"GET /users/oidc/callback":        true,
+	"POST /users/verify-email":        true,
+	"POST /users/resend-verification": true,
+	"POST /users/forgot-password":     true,
+	"POST /users/reset-password":      true,
+	"POST /users/restore-account":     true,
+	"GET /calendar/{token}.ics":       true,
+	// Download links carry their own token
+	"GET /users/exports/{exportId}/download": true,
+}
+
+type testRoute struct {
+	method   string
+	template string
+	path     string
+}
+
+var pathVariable = regexp.MustCompile(`\{[^}]+\}`)
+
+// allRoutes lists every registered route, with path variables set to 1
+func allRoutes(t *testing.T, app *testApp) []testRoute {
+	t.Helper()
+
+	var routes []testRoute
+	err := app.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		for _, method := range methods {
+			routes = append(routes, testRoute{
+				method:   method,
+				template: template,
+				path:     pathVariable.ReplaceAllString(template, "1"),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) == 0 {
+		t.Fatal("no routes registered")
+	}
+	return routes
+}
+
+func TestRoutesRequireAuthentication(t *testing.T) {
+	app := newTestApp(t)
+
+	for _, route := range allRoutes(t, app) {
+		name := route.method + " " + route.template
+		if publicRoutes[name] {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			rec := app.do(route.method, route.path, map[string]string{}, "")
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("without a token got %d, want 401", rec.Code)
+			}
+		})
+	}
+}
+
+func TestAdminRoutesRejectMembers(t *testing.T) {
+	app := newTestApp(t)
+	app.createUser("admin@example.com", policy.RoleAdmin)
+	member := app.createUser("member@example.com", policy.RoleMember)
+	token := app.tokenFor(member)
+
+	for _, route := range allRoutes(t, app) {
+		if !strings.HasPrefix(route.template, "/admin/") {
+			continue
+		}
+		t.Run(route.method+" "+route.template, func(t *testing.T) {
+			rec := app.do(route.method, route.path, map[string]string{}, token)
+			if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusForbidden {
+				t.Errorf("member got %d, want 401 or 403", rec.Code)
+			}
+		})
+	}
+}
+
+// createRole adds a custom role with the permissions
+func (a *testApp) createRole(name string, permissions ...policy.Permission) models.Role {
+	a.t.Helper()
+
+	role := models.Role{Name: name}
+	if err := a.db.Create(&role).Error; err != nil {
+		a.t.Fatalf("create role: %v", err)
+	}
+	for _, permission := range permissions {
+		if err := a.db.Create(&models.RolePermission{RoleID: role.ID, Permission: string(permission)}).Error; err != nil {
+			a.t.Fatalf("create role permission: %v", err)
+		}
+	}
+	return role
+}
+
+func TestUpdateUserRoleCannotGrantMoreThanTheActorHas(t *testing.T) {
+	app := newTestApp(t)
+	app.createRole("manager", append([]policy.Permission{policy.UserManage}, policy.BuiltinRoles[policy.RoleMember]...)...)
+	admin := app.createUser("admin@example.com", policy.RoleAdmin)
+	manager := app.createUser("manager@example.com", "manager")
+	member := app.createUser("member@example.com", policy.RoleMember)
+	token := app.tokenFor(manager)
+
+	tests := []struct {
+		name   string
+		user   models.User
+		role   string
+		status int
+	}{
+		{"promote self to admin", manager, policy.RoleAdmin, http.StatusForbidden},
+		{"promote member to admin", member, policy.RoleAdmin, http.StatusForbidden},
+		{"demote admin", admin, policy.RoleMember, http.StatusForbidden},
+		{"give member own role", member, "manager", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := app.do(http.MethodPut, fmt.Sprintf("/admin/users/%d/role", tt.user.ID), map[string]string{"role": tt.role}, token)
+			if rec.Code != tt.status {
+				t.Fatalf("got %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+		})
+	}
+
+	var reloaded models.User
+	app.db.First(&reloaded, admin.ID)
+	if reloaded.Role != policy.RoleAdmin {
+		t.Errorf("admin role changed to %q", reloaded.Role)
+	}
+}
+
+func TestRoleManagersCannotGrantMoreThanTheyHave(t *testing.T) {
+	app := newTestApp(t)
+	app.createRole("role-manager", policy.RoleManage)
+	custom := app.createRole("helper", policy.TaskReadAny)
+	token := app.tokenFor(app.createUser("roles@example.com", "role-manager"))
+
+	rec := app.do(http.MethodPost, "/admin/roles", map[string]interface{}{
+		"name":        "escalated",
+		"permissions": []policy.Permission{policy.UserManage},
+	}, token)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("creating a role with user.manage got %d, want 403", rec.Code)
+	}
+
+	rec = app.do(http.MethodPut, fmt.Sprintf("/admin/roles/%d", custom.ID), map[string]interface{}{
+		"name":        "helper",
+		"permissions": []policy.Permission{policy.RoleManage},
+	}, token)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("updating a role with permissions the actor lacks got %d, want 403", rec.Code)
+	}
+
+	rec = app.do(http.MethodPost, "/admin/roles", map[string]interface{}{
+		"name":        "junior",
+		"permissions": []policy.Permission{policy.RoleManage},
+	}, token)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("creating a role with the actor's own permissions got %d, want 201: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLastAdminIsFoundByPermissions(t *testing.T) {
+	app := newTestApp(t)
+	app.createRole("owner", policy.All...)
+	admin := app.createUser("admin@example.com", policy.RoleAdmin)
+	token := app.tokenFor(admin)
+
+	rec := app.do(http.MethodPut, fmt.Sprintf("/admin/users/%d/role", admin.ID), map[string]string{"role": policy.RoleMember}, token)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("demoting the last admin got %d, want 409", rec.Code)
+	}
+
+	// A custom role with every permission makes its users admins too
+	app.createUser("owner@example.com", "owner")
+	rec = app.do(http.MethodPut, fmt.Sprintf("/admin/users/%d/role", admin.ID), map[string]string{"role": policy.RoleMember}, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("demoting an admin while an owner is left got %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestRoleOfTheLastAdminsKeepsFullAccess(t *testing.T) {
+	app := newTestApp(t)
+	owner := app.createRole("owner", policy.All...)
+	token := app.tokenFor(app.createUser("owner@example.com", "owner"))
+	path := fmt.Sprintf("/admin/roles/%d", owner.ID)
+
+	rec := app.do(http.MethodPut, path, map[string]interface{}{"name": "owner", "permissions": []policy.Permission{policy.RoleManage}}, token)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("stripping the role of the last admins got %d, want 409", rec.Code)
+	}
+	if !policy.Grants(app.db, "owner", policy.All) {
+		t.Fatal("the role of the last admins lost permissions")
+	}
+
+	// Another admin is left once a user has the built-in admin role
+	app.createUser("admin@example.com", policy.RoleAdmin)
+	rec = app.do(http.MethodPut, path, map[string]interface{}{"name": "owner", "permissions": []policy.Permission{policy.RoleManage}}, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stripping the role while another admin is left got %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUserManagersCannotActOnAdmins(t *testing.T) {
+	app := newTestApp(t)
+	app.createRole("manager", append([]policy.Permission{policy.UserManage}, policy.BuiltinRoles[policy.RoleMember]...)...)
+	admin := app.createUser("admin@example.com", policy.RoleAdmin)
+	app.createUser("second-admin@example.com", policy.RoleAdmin)
+	member := app.createUser("member@example.com", policy.RoleMember)
+	token := app.tokenFor(app.createUser("manager@example.com", "manager"))
+
+	for _, action := range []string{"disable", "enable", "force-password-reset", "unlock"} {
+		t.Run(action, func(t *testing.T) {
+			rec := app.do(http.MethodPost, fmt.Sprintf("/admin/users/%d/%s", admin.ID, action), nil, token)
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("acting on an admin got %d, want 403", rec.Code)
+			}
+			rec = app.do(http.MethodPost, fmt.Sprintf("/admin/users/%d/%s", member.ID, action), nil, token)
+			if rec.Code != http.StatusOK {
+				t.Errorf("acting on a member got %d, want 200: %s", rec.Code, rec.Body.String())
+			}
+		})
+	}
+
+	var reloaded models.User
+	app.db.First(&reloaded, admin.ID)
+	if reloaded.DisabledAt != nil || reloaded.MustChangePassword {
+		t.Errorf("admin was changed: %+v", reloaded)
+	}
+}
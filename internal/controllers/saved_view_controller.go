@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type savedViewRequest struct {
+	Name   string            `json:"name"`
+	Shared bool              `json:"shared"`
+	Filter models.TaskFilter `json:"filter"`
+}
+
+func decodeSavedView(w http.ResponseWriter, r *http.Request) (savedViewRequest, bool) {
+	var requestBody savedViewRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return requestBody, false
+	}
+
+	requestBody.Name = strings.TrimSpace(requestBody.Name)
+	if requestBody.Name == "" {
+		http.Error(w, "View name is required", http.StatusBadRequest)
+		return requestBody, false
+	}
+	if err := validateTaskFilter(requestBody.Filter); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return requestBody, false
+	}
+
+	return requestBody, true
+}
+
+// findVisibleView loads a view the user owns or that is shared
+func findVisibleView(w http.ResponseWriter, db *gorm.DB, viewID string, userID uint) (models.SavedView, bool) {
+	var view models.SavedView
+	if err := db.Where("id = ? AND (user_id = ? OR shared = ?)", viewID, userID, true).First(&view).Error; err != nil {
+		http.Error(w, "View not found", http.StatusNotFound)
+		return view, false
+	}
+	return view, true
+}
+
+// findOwnView loads a view only its owner may change
+func findOwnView(w http.ResponseWriter, db *gorm.DB, viewID string, userID uint) (models.SavedView, bool) {
+	view, ok := findVisibleView(w, db, viewID, userID)
+	if ok && view.UserID != userID {
+		http.Error(w, "Unauthorized to change this view", http.StatusUnauthorized)
+		return view, false
+	}
+	return view, ok
+}
+
+// GetSavedViews lists the views of the user and the shared views
+func GetSavedViews(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		views := []models.SavedView{}
+		if err := db.Where("user_id = ? OR shared = ?", claims.UserID, true).Order("name, id").Find(&views).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, views)
+	}
+}
+
+// CreateSavedView saves a task filter under a name
+func CreateSavedView(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		requestBody, ok := decodeSavedView(w, r)
+		if !ok {
+			return
+		}
+
+		view := models.SavedView{
+			UserID: claims.UserID,
+			Name:   requestBody.Name,
+			Shared: requestBody.Shared,
+			Filter: requestBody.Filter,
+		}
+		if err := db.Create(&view).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, view)
+	}
+}
+
+// UpdateSavedView replaces the name, sharing and filter of a view
+func UpdateSavedView(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		view, ok := findOwnView(w, db, mux.Vars(r)["viewId"], claims.UserID)
+		if !ok {
+			return
+		}
+
+		requestBody, ok := decodeSavedView(w, r)
+		if !ok {
+			return
+		}
+
+		view.Name = requestBody.Name
+		view.Shared = requestBody.Shared
+		view.Filter = requestBody.Filter
+		if err := db.Save(&view).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, view)
+	}
+}
+
+// DeleteSavedView deletes a view
+func DeleteSavedView(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		view, ok := findOwnView(w, db, mux.Vars(r)["viewId"], claims.UserID)
+		if !ok {
+			return
+		}
+
+		if err := db.Delete(&view).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "View has been successfully deleted"})
+	}
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/search"
+	"gorm.io/gorm"
+)
+
+// Search finds the tasks and comments the user can see that match q. The
+// results can be narrowed with category_id and status.
+func Search(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		text := strings.TrimSpace(r.URL.Query().Get("q"))
+		if text == "" {
+			http.Error(w, "q is required", http.StatusBadRequest)
+			return
+		}
+
+		page, limit := parsePagination(r)
+		query := search.Query{
+			Text:     text,
+			UserID:   claims.UserID,
+			AllTasks: policy.Has(db, actor, policy.TaskReadAny),
+			Page:     page,
+			Limit:    limit,
+		}
+
+		if value := r.URL.Query().Get("category_id"); value != "" {
+			categoryID, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid category ID", http.StatusBadRequest)
+				return
+			}
+			query.CategoryID = uint(categoryID)
+		}
+		if value := r.URL.Query().Get("status"); value != "" {
+			status, err := strconv.ParseBool(value)
+			if err != nil {
+				http.Error(w, "status must be true or false", http.StatusBadRequest)
+				return
+			}
+			query.Status = &status
+		}
+
+		results, err := search.Search(db, query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, results)
+	}
+}
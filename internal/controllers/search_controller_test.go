@@ -0,0 +1,49 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/search"
+)
+
+func TestSearchTreatsWildcardsLiterally(t *testing.T) {
+	app := newTestApp(t)
+	user := app.createUser("member@example.com", policy.RoleMember)
+	token := app.tokenFor(user)
+	category := app.createCategory("Work")
+	for _, title := range []string{"Raise prices by 50%", "Raise prices by 50 euros", "Rename file_name", "Rename filename", `Fix C:\temp path`} {
+		app.db.Create(&models.Task{Title: title, CategoryID: category.ID, UserID: user.ID})
+	}
+
+	tests := map[string][]string{
+		"50%":       {"Raise prices by 50%"},
+		"file_name": {"Rename file_name"},
+		`c:\temp`:   {`Fix C:\temp path`},
+		"%":         {"Raise prices by 50%"},
+	}
+	for q, want := range tests {
+		rec := app.do(http.MethodGet, "/search?q="+url.QueryEscape(q), nil, token)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("search %q returned %d: %s", q, rec.Code, rec.Body.String())
+		}
+		var results search.Results
+		if err := json.NewDecoder(rec.Body).Decode(&results); err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, hit := range results.Hits {
+			got = append(got, hit.Title)
+		}
+		if len(got) != len(want) || (len(got) > 0 && got[0] != want[0]) {
+			t.Errorf("search %q found %q, want %q", q, got, want)
+		}
+		if results.Truncated {
+			t.Errorf("search %q is marked truncated", q)
+		}
+	}
+}
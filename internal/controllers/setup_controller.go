@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// AdminExists reports whether at least one admin account exists
+func AdminExists(db *gorm.DB) bool {
+	var count int64
+	db.Model(&models.User{}).Where("role IN ?", policy.FullAccessRoles(db)).Count(&count)
+	return count > 0
+}
+
+// CreateAdminUser creates an admin account. The admin has to change the
+// password on first login.
+func CreateAdminUser(db *gorm.DB, fullName, email, password string) (models.User, error) {
+	email = config.NormalizeEmail(email)
+	if fullName == "" || email == "" {
+		return models.User{}, errors.New("Full name and email are required")
+	}
+	if err := config.ValidateEmail(email); err != nil {
+		return models.User{}, err
+	}
+	if err := config.Passwords.Validate(password); err != nil {
+		return models.User{}, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	verifiedAt := time.Now()
+	admin := models.User{
+		FullName:           fullName,
+		Email:              email,
+		Password:           string(hashedPassword),
+		Role:               "admin",
+		EmailVerifiedAt:    &verifiedAt,
+		MustChangePassword: true,
+	}
+	err = db.Create(&admin).Error
+	return admin, err
+}
+
+// SecureLegacyAdmin forces a password change on the admin account that older
+// versions seeded with a well-known password.
+func SecureLegacyAdmin(db *gorm.DB) {
+	var admin models.User
+	if err := db.Where("email = ? AND role = ?", "admin@gmail.com", "admin").First(&admin).Error; err != nil {
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte("admin123")) != nil {
+		return
+	}
+
+	log.Println("Warning: admin@gmail.com still uses the default password, it must be changed on next login")
+	db.Model(&admin).Update("must_change_password", true)
+}
+
+// SetupAdmin creates the first admin account. It needs the SETUP_TOKEN from
+// the environment and stops working once an admin exists.
+func SetupAdmin(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setupToken := os.Getenv("SETUP_TOKEN")
+		if setupToken == "" || AdminExists(db) {
+			http.Error(w, "Setup is not available", http.StatusNotFound)
+			return
+		}
+
+		var requestBody struct {
+			Token    string `json:"token"`
+			FullName string `json:"full_name"`
+			Email    string `json:"email"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if subtle.ConstantTimeCompare([]byte(requestBody.Token), []byte(setupToken)) != 1 {
+			http.Error(w, "Invalid setup token", http.StatusUnauthorized)
+			return
+		}
+
+		admin, err := CreateAdminUser(db, requestBody.FullName, requestBody.Email, requestBody.Password)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, map[string]interface{}{
+			"id":         admin.ID,
+			"full_name":  admin.FullName,
+			"email":      admin.Email,
+			"created_at": admin.CreatedAt.Format(time.RFC3339),
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/analytics"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+const defaultVelocitySprints = 6
+
+type sprintRequest struct {
+	Name      string `json:"name"`
+	Goal      string `json:"goal"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// decodeSprint reads a sprint. The dates are dates or RFC 3339 times, an end
+// date includes the whole day.
+func decodeSprint(w http.ResponseWriter, r *http.Request, sprint *models.Sprint) bool {
+	var requestBody sprintRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+
+	requestBody.Name = strings.TrimSpace(requestBody.Name)
+	if requestBody.Name == "" {
+		http.Error(w, "Sprint name is required", http.StatusBadRequest)
+		return false
+	}
+	startDate, err := parseFilterTime(requestBody.StartDate, false)
+	if err != nil {
+		http.Error(w, "start_date must be a date (2006-01-02) or an RFC 3339 time", http.StatusBadRequest)
+		return false
+	}
+	endDate, err := parseFilterTime(requestBody.EndDate, true)
+	if err != nil {
+		http.Error(w, "end_date must be a date (2006-01-02) or an RFC 3339 time", http.StatusBadRequest)
+		return false
+	}
+	if !endDate.After(startDate) {
+		http.Error(w, "end_date must be after start_date", http.StatusBadRequest)
+		return false
+	}
+	// The burndown has a point for every day of the sprint
+	if endDate.Sub(startDate) > maxAnalyticsDays*24*time.Hour {
+		http.Error(w, "A sprint can last at most 366 days", http.StatusBadRequest)
+		return false
+	}
+
+	sprint.Name = requestBody.Name
+	sprint.Goal = requestBody.Goal
+	sprint.StartDate = startDate
+	sprint.EndDate = endDate
+	return true
+}
+
+func findSprint(w http.ResponseWriter, r *http.Request, db *gorm.DB) (models.Sprint, bool) {
+	var sprint models.Sprint
+	if err := db.First(&sprint, mux.Vars(r)["sprintId"]).Error; err != nil {
+		http.Error(w, "Sprint not found", http.StatusNotFound)
+		return sprint, false
+	}
+	return sprint, true
+}
+
+// GetSprints lists the sprints of the board, latest first
+func GetSprints(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := config.Authenticate(r, db); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		sprints := []models.Sprint{}
+		if err := db.Order("start_date DESC, id DESC").Find(&sprints).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, sprints)
+	}
+}
+
+// GetSprint returns a sprint. Its tasks are listed with GET /tasks?sprint_id=
+func GetSprint(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := config.Authenticate(r, db); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		sprint, ok := findSprint(w, r, db)
+		if !ok {
+			return
+		}
+
+		config.SendJSONResponse(w, sprint)
+	}
+}
+
+// CreateSprint creates a new sprint
+func CreateSprint(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.SprintManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var sprint models.Sprint
+		if !decodeSprint(w, r, &sprint) {
+			return
+		}
+		if err := db.Create(&sprint).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, sprint)
+	}
+}
+
+// UpdateSprint replaces the name, goal and dates of a sprint
+func UpdateSprint(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.SprintManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		sprint, ok := findSprint(w, r, db)
+		if !ok {
+			return
+		}
+		if !decodeSprint(w, r, &sprint) {
+			return
+		}
+		if err := db.Save(&sprint).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, sprint)
+	}
+}
+
+// DeleteSprint deletes a sprint. Its tasks go back to the backlog.
+func DeleteSprint(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.SprintManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		sprint, ok := findSprint(w, r, db)
+		if !ok {
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Task{}).Where("sprint_id = ?", sprint.ID).Update("sprint_id", nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&sprint).Error
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Sprint has been successfully deleted"})
+	}
+}
+
+// AddSprintTasks plans tasks in a sprint, taking them out of any other sprint
+func AddSprintTasks(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.SprintManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		sprint, ok := findSprint(w, r, db)
+		if !ok {
+			return
+		}
+
+		var requestBody struct {
+			TaskIDs []uint `json:"task_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(requestBody.TaskIDs) == 0 {
+			http.Error(w, "task_ids is required", http.StatusBadRequest)
+			return
+		}
+
+		var count int64
+		if err := db.Model(&models.Task{}).Where("id IN ?", requestBody.TaskIDs).Count(&count).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		seen := map[uint]bool{}
+		for _, taskID := range requestBody.TaskIDs {
+			seen[taskID] = true
+		}
+		if int(count) != len(seen) {
+			http.Error(w, "Task not found", http.StatusBadRequest)
+			return
+		}
+
+		if err := db.Model(&models.Task{}).Where("id IN ?", requestBody.TaskIDs).Update("sprint_id", sprint.ID).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Tasks have been added to the sprint"})
+	}
+}
+
+// RemoveSprintTask takes a task out of a sprint, back to the backlog
+func RemoveSprintTask(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.SprintManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		sprint, ok := findSprint(w, r, db)
+		if !ok {
+			return
+		}
+
+		result := db.Model(&models.Task{}).Where("id = ? AND sprint_id = ?", mux.Vars(r)["taskId"], sprint.ID).Update("sprint_id", nil)
+		if result.Error != nil {
+			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+			return
+		}
+		if result.RowsAffected == 0 {
+			http.Error(w, "Task not found in this sprint", http.StatusNotFound)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Task has been removed from the sprint"})
+	}
+}
+
+// GetSprintBurndown returns the remaining estimate of a sprint for each day
+func GetSprintBurndown(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := config.Authenticate(r, db); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		sprint, ok := findSprint(w, r, db)
+		if !ok {
+			return
+		}
+
+		burndown, err := analytics.SprintBurndown(db, sprint, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, burndown)
+	}
+}
+
+// GetVelocity returns the planned and completed estimate of the last ended
+// sprints, oldest first
+func GetVelocity(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := config.Authenticate(r, db); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		count := defaultVelocitySprints
+		if value := r.URL.Query().Get("count"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 52 {
+				http.Error(w, "count must be between 1 and 52", http.StatusBadRequest)
+				return
+			}
+			count = n
+		}
+
+		var sprints []models.Sprint
+		if err := db.Where("end_date < ?", time.Now()).Order("end_date DESC, id DESC").Limit(count).Find(&sprints).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for i, j := 0, len(sprints)-1; i < j; i, j = i+1, j-1 {
+			sprints[i], sprints[j] = sprints[j], sprints[i]
+		}
+
+		velocity, err := analytics.SprintsVelocity(db, sprints)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, velocity)
+	}
+}
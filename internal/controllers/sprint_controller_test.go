@@ -0,0 +1,22 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+)
+
+func TestSprintsCannotLastMoreThanAYear(t *testing.T) {
+	app := newTestApp(t)
+	token := app.tokenFor(app.createUser("admin@example.com", policy.RoleAdmin))
+
+	rec := app.do(http.MethodPost, "/sprints", map[string]string{"name": "Forever", "start_date": "2030-01-01", "end_date": "2099-12-31"}, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("creating a sprint of 70 years returned %d, want 400", rec.Code)
+	}
+	rec = app.do(http.MethodPost, "/sprints", map[string]string{"name": "Year", "start_date": "2030-01-01", "end_date": "2030-12-31"}, token)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("creating a sprint of a year returned %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/analytics"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/notifications"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/recurrence"
+	"gorm.io/gorm"
+)
+
+// Operations of POST /tasks/bulk
+const (
+	BulkSetStatus    = "set_status"
+	BulkMoveCategory = "move_category"
+	BulkDelete       = "delete"
+	BulkAddLabel     = "add_label"
+	BulkAssign       = "assign"
+)
+
+// maxBulkTasks is the most tasks one bulk request may change
+const maxBulkTasks = 500
+
+type BulkTaskRequest struct {
+	TaskIDs    []uint `json:"task_ids"`
+	Operation  string `json:"operation"`
+	Status     *bool  `json:"status"`
+	CategoryID uint   `json:"category_id"`
+	LabelID    uint   `json:"label_id"`
+	AssigneeID *uint  `json:"assignee_id"`
+	// AllOrNothing rolls every change back when one task fails
+	AllOrNothing bool `json:"all_or_nothing"`
+}
+
+type BulkTaskResult struct {
+	TaskID uint   `json:"task_id"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+type BulkTaskResponse struct {
+	Operation  string           `json:"operation"`
+	Succeeded  int              `json:"succeeded"`
+	Failed     int              `json:"failed"`
+	RolledBack bool             `json:"rolled_back"`
+	Results    []BulkTaskResult `json:"results"`
+}
+
+var errBulkRollback = errors.New("rollback")
+
+// BulkUpdateTasks applies one operation to many tasks in a transaction. The
+// permission of the caller is checked for every task and the outcome of
+// each one is reported. Tasks that fail are skipped unless all_or_nothing
+// is set, then nothing is changed.
+func BulkUpdateTasks(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var requestBody BulkTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(requestBody.TaskIDs) == 0 {
+			http.Error(w, "task_ids is required", http.StatusBadRequest)
+			return
+		}
+		if len(requestBody.TaskIDs) > maxBulkTasks {
+			http.Error(w, "Too many tasks, send at most 500", http.StatusBadRequest)
+			return
+		}
+
+		// Check the operation and its arguments once for every task
+		var category models.Category
+		var label models.Label
+		switch requestBody.Operation {
+		case BulkSetStatus:
+			if requestBody.Status == nil {
+				http.Error(w, "status is required", http.StatusBadRequest)
+				return
+			}
+		case BulkMoveCategory:
+			if err := db.First(&category, requestBody.CategoryID).Error; err != nil {
+				http.Error(w, "Category not found", http.StatusBadRequest)
+				return
+			}
+		case BulkAddLabel:
+			if err := db.First(&label, requestBody.LabelID).Error; err != nil {
+				http.Error(w, "Label not found", http.StatusBadRequest)
+				return
+			}
+		case BulkAssign:
+			if requestBody.AssigneeID != nil {
+				var assignee models.User
+				if err := db.First(&assignee, *requestBody.AssigneeID).Error; err != nil || config.AccountBlocked(assignee) != nil {
+					http.Error(w, "Assignee not found", http.StatusBadRequest)
+					return
+				}
+			}
+		case BulkDelete:
+		default:
+			http.Error(w, "operation must be set_status, move_category, delete, add_label or assign", http.StatusBadRequest)
+			return
+		}
+
+		permission := policy.TaskUpdate
+		if requestBody.Operation == BulkDelete {
+			permission = policy.TaskDelete
+		}
+
+		response := BulkTaskResponse{Operation: requestBody.Operation, Results: []BulkTaskResult{}}
+		// Tasks whose move, assignment or completion has to be followed up
+		var notify []models.Task
+		succeeded := 0
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			seen := map[uint]bool{}
+			for _, taskID := range requestBody.TaskIDs {
+				if seen[taskID] {
+					continue
+				}
+				seen[taskID] = true
+
+				var task models.Task
+				if err := tx.First(&task, taskID).Error; err != nil {
+					response.Results = append(response.Results, BulkTaskResult{TaskID: taskID, Error: "Task not found"})
+					continue
+				}
+				if !policy.Can(tx, actor, permission, task) {
+					response.Results = append(response.Results, BulkTaskResult{TaskID: taskID, Error: "Unauthorized to change this task"})
+					continue
+				}
+
+				var err error
+				switch requestBody.Operation {
+				case BulkSetStatus:
+					if *requestBody.Status && !task.Status {
+						notify = append(notify, task)
+					}
+					if err = analytics.RecordStatus(tx, task, *requestBody.Status, actor.User.ID); err == nil {
+						err = tx.Model(&task).Update("status", *requestBody.Status).Error
+					}
+				case BulkMoveCategory:
+					if task.CategoryID != category.ID {
+						notify = append(notify, task)
+					}
+					if err = analytics.RecordMove(tx, task, category.ID, actor.User.ID); err == nil {
+						err = tx.Model(&models.Task{}).Where("id = ?", task.ID).Update("category_id", category.ID).Error
+					}
+				case BulkDelete:
+					err = tx.Delete(&task).Error
+				case BulkAddLabel:
+					err = tx.Model(&task).Association("Labels").Append(&label)
+				case BulkAssign:
+					if requestBody.AssigneeID != nil && (task.AssigneeID == nil || *task.AssigneeID != *requestBody.AssigneeID) {
+						task.AssigneeID = requestBody.AssigneeID
+						notify = append(notify, task)
+					}
+					err = tx.Model(&models.Task{}).Where("id = ?", task.ID).Update("assignee_id", requestBody.AssigneeID).Error
+				}
+				if err != nil {
+					return err
+				}
+
+				response.Results = append(response.Results, BulkTaskResult{TaskID: taskID, OK: true})
+				succeeded++
+			}
+
+			if requestBody.AllOrNothing && succeeded < len(response.Results) {
+				return errBulkRollback
+			}
+			return nil
+		})
+		if err != nil && err != errBulkRollback {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		for _, result := range response.Results {
+			if result.OK {
+				response.Succeeded++
+			} else {
+				response.Failed++
+			}
+		}
+
+		if err == errBulkRollback {
+			response.RolledBack = true
+			config.SendJSONResponse(w, response)
+			return
+		}
+
+		// Follow up only once the changes are saved
+		for _, task := range notify {
+			switch requestBody.Operation {
+			case BulkSetStatus:
+				task.Status = true
+				recurrence.TaskCompleted(db, task)
+			case BulkMoveCategory:
+				notifications.TaskMoved(db, task, category, actor.User)
+			case BulkAssign:
+				notifications.TaskAssigned(db, task, actor.User)
+			}
+		}
+
+		config.SendJSONResponse(w, response)
+	}
+}
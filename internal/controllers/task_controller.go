@@ -6,36 +6,89 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/analytics"
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/notifications"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/recurrence"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/timetracking"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type CreateTaskResponse struct {
-	ID          uint      `json:"id"`
-	Title       string    `json:"title"`
-	Status      bool      `json:"status"`
-	Description string    `json:"description"`
-	UserID      uint      `json:"user_id"`
-	CategoryID  uint      `json:"category_id"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Status      bool       `json:"status"`
+	Description string     `json:"description"`
+	UserID      uint       `json:"user_id"`
+	CategoryID  uint       `json:"category_id"`
+	AssigneeID  *uint      `json:"assignee_id"`
+	DueDate     *time.Time `json:"due_date"`
+	Estimate    *float64   `json:"estimate"`
+	SprintID    *uint      `json:"sprint_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// createTaskRequest holds the fields a client may set on a new task. Tasks
+// are never decoded directly, as gorm would also save nested users.
+type createTaskRequest struct {
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	CategoryID  uint       `json:"category_id"`
+	AssigneeID  *uint      `json:"assignee_id"`
+	DueDate     *time.Time `json:"due_date"`
+	Estimate    *float64   `json:"estimate"`
+	SprintID    *uint      `json:"sprint_id"`
+	LabelIDs    []uint     `json:"label_ids"`
 }
 
 func CreateTask(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Autentikasi pengguna
-		claims, err := config.Authenticate(r, db)
+		claims, actor, err := config.AuthenticateActor(r, db)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
 		}
 
-		var task models.Task
-		if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		if !policy.Has(db, actor, policy.TaskCreate) {
+			http.Error(w, "Unauthorized to create tasks", http.StatusUnauthorized)
+			return
+		}
+
+		var requestBody createTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		task := models.Task{
+			Title:       requestBody.Title,
+			Description: requestBody.Description,
+			CategoryID:  requestBody.CategoryID,
+			AssigneeID:  requestBody.AssigneeID,
+			DueDate:     requestBody.DueDate,
+			Estimate:    requestBody.Estimate,
+			SprintID:    requestBody.SprintID,
+		}
+		var ok bool
+		if task.Labels, ok = findLabels(w, db, requestBody.LabelIDs); !ok {
+			return
+		}
+
+		// Fields sent with the task override the template
+		var template models.TaskTemplate
+		if templateID := r.URL.Query().Get("template"); templateID != "" {
+			if template, ok = findTaskTemplate(w, db, templateID); !ok {
+				return
+			}
+			if err := applyTaskTemplate(&task, template, actor.User, time.Now()); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
 
 		// Check if category exists
 		var category models.Category
@@ -44,15 +97,59 @@ func CreateTask(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		// Check if assignee exists and can still log in
+		if task.AssigneeID != nil {
+			var assignee models.User
+			if err := db.First(&assignee, *task.AssigneeID).Error; err != nil || config.AccountBlocked(assignee) != nil {
+				http.Error(w, "Assignee not found", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if task.Estimate != nil && *task.Estimate < 0 {
+			http.Error(w, "Estimate must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		// Planning a task into a sprint is up to sprint managers
+		if task.SprintID != nil {
+			if !policy.Has(db, actor, policy.SprintManage) {
+				http.Error(w, "Unauthorized to plan tasks into sprints", http.StatusUnauthorized)
+				return
+			}
+			var sprint models.Sprint
+			if err := db.First(&sprint, *task.SprintID).Error; err != nil {
+				http.Error(w, "Sprint not found", http.StatusBadRequest)
+				return
+			}
+		}
+
 		task.UserID = claims.UserID // Set user ID from JWT claims
 		task.Status = false         // Set status to false by default
 
 		// Save task to database
-		if err := db.Create(&task).Error; err != nil {
+		labels := task.Labels
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit(clause.Associations).Create(&task).Error; err != nil {
+				return err
+			}
+			if len(labels) > 0 {
+				if err := tx.Model(&task).Association("Labels").Append(labels); err != nil {
+					return err
+				}
+			}
+			if err := createTemplateChecklist(tx, task, template); err != nil {
+				return err
+			}
+			return analytics.RecordCreated(tx, task, actor.User.ID)
+		})
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
+		notifications.TaskAssigned(db, task, actor.User)
+
 		// Create the response struct with only the required fields
 		response := CreateTaskResponse{
 			ID:          task.ID,
@@ -61,6 +158,10 @@ func CreateTask(db *gorm.DB) http.HandlerFunc {
 			Description: task.Description,
 			UserID:      task.UserID,
 			CategoryID:  task.CategoryID,
+			AssigneeID:  task.AssigneeID,
+			DueDate:     task.DueDate,
+			Estimate:    task.Estimate,
+			SprintID:    task.SprintID,
 			CreatedAt:   task.CreatedAt,
 		}
 
@@ -70,20 +171,28 @@ func CreateTask(db *gorm.DB) http.HandlerFunc {
 }
 
 type GetTasksResponse struct {
-	ID          uint      `json:"id"`
-	Title       string    `json:"title"`
-	Status      bool      `json:"status"`
-	Description string    `json:"description"`
-	UserID      uint      `json:"user_id"`
-	CategoryID  uint      `json:"category_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	User        struct {
+	ID              uint       `json:"id"`
+	Title           string     `json:"title"`
+	Status          bool       `json:"status"`
+	Description     string     `json:"description"`
+	UserID          uint       `json:"user_id"`
+	CategoryID      uint       `json:"category_id"`
+	AssigneeID      *uint      `json:"assignee_id"`
+	DueDate         *time.Time `json:"due_date"`
+	Estimate        *float64   `json:"estimate"`
+	SprintID        *uint      `json:"sprint_id"`
+	TimeSpent       int64      `json:"time_spent_seconds"`
+	RecurringTaskID *uint      `json:"recurring_task_id"`
+	CreatedAt       time.Time  `json:"created_at"`
+	User            struct {
 		ID       uint   `json:"id"`
 		Email    string `json:"email"`
 		FullName string `json:"full_name"`
 	} `json:"User"`
 }
 
+// GetTasks lists the tasks of the user, narrowed by the filter query
+// parameters or by a saved view given with view
 func GetTasks(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		claims, err := config.Authenticate(r, db)
@@ -92,44 +201,88 @@ func GetTasks(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		// Query parameters are applied on top of the saved view
+		var filter models.TaskFilter
+		if viewID := r.URL.Query().Get("view"); viewID != "" {
+			view, ok := findVisibleView(w, db, viewID, claims.UserID)
+			if !ok {
+				return
+			}
+			filter = view.Filter
+		}
+		filter, err = parseTaskFilter(r.URL.Query(), filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Users see the tasks they own and the tasks assigned to them
+		query := db.Where("(tasks.user_id = ? OR tasks.assignee_id = ?)", claims.UserID, claims.UserID)
 		var tasks []models.Task
-		if err := db.Where("user_id = ?", claims.UserID).Preload("User").Find(&tasks).Error; err != nil {
+		if err := applyTaskFilter(query, filter).Preload("User").Find(&tasks).Error; err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// Create a slice for the custom response
-		var response []GetTasksResponse
-
-		// Map tasks to custom response struct
-		for _, task := range tasks {
-			response = append(response, GetTasksResponse{
-				ID:          task.ID,
-				Title:       task.Title,
-				Status:      task.Status,
-				Description: task.Description,
-				UserID:      task.UserID,
-				CategoryID:  task.CategoryID,
-				CreatedAt:   task.CreatedAt,
-				User: struct {
-					ID       uint   `json:"id"`
-					Email    string `json:"email"`
-					FullName string `json:"full_name"`
-				}{
-					ID:       task.User.ID,
-					Email:    task.User.Email,
-					FullName: task.User.FullName,
-				},
-			})
+		response, err := taskListResponse(db, tasks)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		config.SendJSONResponse(w, response)
 	}
 }
 
+// taskListResponse maps tasks with their User preloaded to GetTasksResponse,
+// adding the time spent on them
+func taskListResponse(db *gorm.DB, tasks []models.Task) ([]GetTasksResponse, error) {
+	taskIDs := make([]uint, 0, len(tasks))
+	for _, task := range tasks {
+		taskIDs = append(taskIDs, task.ID)
+	}
+	totals, err := timetracking.Totals(db, taskIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a slice for the custom response
+	var response []GetTasksResponse
+
+	// Map tasks to custom response struct
+	for _, task := range tasks {
+		response = append(response, GetTasksResponse{
+			ID:              task.ID,
+			Title:           task.Title,
+			Status:          task.Status,
+			Description:     task.Description,
+			UserID:          task.UserID,
+			CategoryID:      task.CategoryID,
+			AssigneeID:      task.AssigneeID,
+			DueDate:         task.DueDate,
+			Estimate:        task.Estimate,
+			SprintID:        task.SprintID,
+			TimeSpent:       int64(totals[task.ID].Seconds()),
+			RecurringTaskID: task.RecurringTaskID,
+			CreatedAt:       task.CreatedAt,
+			User: struct {
+				ID       uint   `json:"id"`
+				Email    string `json:"email"`
+				FullName string `json:"full_name"`
+			}{
+				ID:       task.User.ID,
+				Email:    task.User.Email,
+				FullName: task.User.FullName,
+			},
+		})
+	}
+
+	return response, nil
+}
+
 func UpdateTask(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims, err := config.Authenticate(r, db)
+		_, actor, err := config.AuthenticateActor(r, db)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -143,13 +296,20 @@ func UpdateTask(db *gorm.DB) http.HandlerFunc {
 		}
 
 		var updateData struct {
-			Title       string `json:"title"`
-			Description string `json:"description"`
+			Title       string     `json:"title"`
+			Description string     `json:"description"`
+			AssigneeID  *uint      `json:"assignee_id"`
+			DueDate     *time.Time `json:"due_date"`
+			Estimate    *float64   `json:"estimate"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&updateData); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if updateData.Estimate != nil && *updateData.Estimate < 0 {
+			http.Error(w, "Estimate must not be negative", http.StatusBadRequest)
+			return
+		}
 
 		var task models.Task
 		if err := db.First(&task, taskID).Error; err != nil {
@@ -157,11 +317,26 @@ func UpdateTask(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		if task.UserID != claims.UserID {
+		if !policy.Can(db, actor, policy.TaskUpdate, task) {
 			http.Error(w, "Unauthorized to update this task", http.StatusUnauthorized)
 			return
 		}
 
+		reassigned := false
+		if updateData.AssigneeID != nil {
+			var assignee models.User
+			if err := db.First(&assignee, *updateData.AssigneeID).Error; err != nil || config.AccountBlocked(assignee) != nil {
+				http.Error(w, "Assignee not found", http.StatusBadRequest)
+				return
+			}
+			reassigned = task.AssigneeID == nil || *task.AssigneeID != *updateData.AssigneeID
+		}
+
+		// A new due date gets a new reminder
+		if updateData.DueDate != nil {
+			db.Model(&task).UpdateColumn("due_reminder_sent_at", nil)
+		}
+
 		// Update task with new data
 		db.Model(&task).Updates(updateData)
 
@@ -169,15 +344,23 @@ func UpdateTask(db *gorm.DB) http.HandlerFunc {
 		var updatedTask models.Task
 		db.First(&updatedTask, taskID)
 
+		if reassigned {
+			notifications.TaskAssigned(db, updatedTask, actor.User)
+		}
+
 		// Create the response struct with only the required fields
 		response := struct {
-			ID          uint      `json:"id"`
-			Title       string    `json:"title"`
-			Description string    `json:"description"`
-			Status      bool      `json:"status"`
-			UserID      uint      `json:"user_id"`
-			CategoryID  uint      `json:"category_id"`
-			UpdatedAt   time.Time `json:"updated_at"`
+			ID          uint       `json:"id"`
+			Title       string     `json:"title"`
+			Description string     `json:"description"`
+			Status      bool       `json:"status"`
+			UserID      uint       `json:"user_id"`
+			CategoryID  uint       `json:"category_id"`
+			AssigneeID  *uint      `json:"assignee_id"`
+			DueDate     *time.Time `json:"due_date"`
+			Estimate    *float64   `json:"estimate"`
+			SprintID    *uint      `json:"sprint_id"`
+			UpdatedAt   time.Time  `json:"updated_at"`
 		}{
 			ID:          updatedTask.ID,
 			Title:       updatedTask.Title,
@@ -185,6 +368,10 @@ func UpdateTask(db *gorm.DB) http.HandlerFunc {
 			Status:      updatedTask.Status,
 			UserID:      updatedTask.UserID,
 			CategoryID:  updatedTask.CategoryID,
+			AssigneeID:  updatedTask.AssigneeID,
+			DueDate:     updatedTask.DueDate,
+			Estimate:    updatedTask.Estimate,
+			SprintID:    updatedTask.SprintID,
 			UpdatedAt:   updatedTask.UpdatedAt,
 		}
 
@@ -204,7 +391,7 @@ type UpdateTaskStatusResponse struct {
 
 func UpdateTaskStatus(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims, err := config.Authenticate(r, db)
+		_, actor, err := config.AuthenticateActor(r, db)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -231,13 +418,19 @@ func UpdateTaskStatus(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		if task.UserID != claims.UserID {
+		if !policy.Can(db, actor, policy.TaskUpdate, task) {
 			http.Error(w, "Unauthorized to update status of this task", http.StatusUnauthorized)
 			return
 		}
 
+		completed := updateData.Status && !task.Status
+		analytics.RecordStatus(db, task, updateData.Status, actor.User.ID)
 		db.Model(&task).Update("status", updateData.Status)
 
+		if completed {
+			recurrence.TaskCompleted(db, task)
+		}
+
 		// Create the response struct with only the required fields
 		response := UpdateTaskStatusResponse{
 			ID:          task.ID,
@@ -255,7 +448,7 @@ func UpdateTaskStatus(db *gorm.DB) http.HandlerFunc {
 
 func UpdateTaskCategory(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims, err := config.Authenticate(r, db)
+		_, actor, err := config.AuthenticateActor(r, db)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -288,15 +481,22 @@ func UpdateTaskCategory(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		// Check if the task belongs to the authenticated user
-		if task.UserID != claims.UserID {
+		// Check if the authenticated user may change the task
+		if !policy.Can(db, actor, policy.TaskUpdate, task) {
 			http.Error(w, "Unauthorized to change the category of this task", http.StatusUnauthorized)
 			return
 		}
 
+		moved := task.CategoryID != updateData.CategoryID
+		analytics.RecordMove(db, task, updateData.CategoryID, actor.User.ID)
+
 		// Update the category ID in the task
 		db.Model(&task).Update("category_id", updateData.CategoryID)
 
+		if moved {
+			notifications.TaskMoved(db, task, category, actor.User)
+		}
+
 		// Fetch the updated task with preloaded user data
 		var updatedTask models.Task
 		if err := db.Where("id = ?", taskId).Preload("User").First(&updatedTask).Error; err != nil {
@@ -330,7 +530,7 @@ func UpdateTaskCategory(db *gorm.DB) http.HandlerFunc {
 func DeleteTask(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Authenticate the user
-		claims, err := config.Authenticate(r, db)
+		_, actor, err := config.AuthenticateActor(r, db)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusUnauthorized)
 			return
@@ -351,8 +551,8 @@ func DeleteTask(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
-		// Check if the task belongs to the authenticated user
-		if task.UserID != claims.UserID {
+		// Check if the authenticated user may delete the task
+		if !policy.Can(db, actor, policy.TaskDelete, task) {
 			http.Error(w, "Unauthorized to delete this task", http.StatusUnauthorized)
 			return
 		}
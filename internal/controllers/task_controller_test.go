@@ -0,0 +1,136 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+)
+
+func TestTasksCannotBeAssignedToBlockedUsers(t *testing.T) {
+	app := newTestApp(t)
+	category := app.createCategory("Todo")
+	owner := app.createUser("owner@example.com", policy.RoleMember)
+	token := app.tokenFor(owner)
+	task := models.Task{Title: "Assigned", CategoryID: category.ID, UserID: owner.ID}
+	app.db.Create(&task)
+
+	now := time.Now()
+	disabled := app.createUser("disabled@example.com", policy.RoleMember)
+	app.db.Model(&disabled).Update("disabled_at", now)
+	leaving := app.createUser("leaving@example.com", policy.RoleMember)
+	app.db.Model(&leaving).Update("deletion_scheduled_at", now.Add(time.Hour))
+
+	for _, user := range []models.User{disabled, leaving} {
+		t.Run(user.Email, func(t *testing.T) {
+			rec := app.do(http.MethodPost, "/tasks", map[string]interface{}{"title": "New", "category_id": category.ID, "assignee_id": user.ID}, token)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("creating a task for them got %d, want 400", rec.Code)
+			}
+			rec = app.do(http.MethodPut, fmt.Sprintf("/tasks/%d", task.ID), map[string]interface{}{"title": "Assigned", "assignee_id": user.ID}, token)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("assigning a task to them got %d, want 400", rec.Code)
+			}
+		})
+	}
+}
+
+func TestCreateTaskIgnoresNestedRecords(t *testing.T) {
+	app := newTestApp(t)
+	category := app.createCategory("Todo")
+	token := app.tokenFor(app.createUser("member@example.com", policy.RoleMember))
+
+	rec := app.do(http.MethodPost, "/tasks", map[string]interface{}{
+		"title":       "Sneaky",
+		"category_id": category.ID,
+		"assignee":    map[string]string{"email": "evil@example.com", "full_name": "Evil", "role": "admin", "password": "x"},
+		"user":        map[string]string{"email": "evil2@example.com", "role": "admin"},
+		"labels":      []map[string]string{{"name": "made-up"}},
+	}, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create task returned %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var users, labels int64
+	app.db.Model(&models.User{}).Count(&users)
+	app.db.Model(&models.Label{}).Count(&labels)
+	if users != 1 || labels != 0 {
+		t.Fatalf("creating a task created %d users and %d labels", users-1, labels)
+	}
+}
+
+func TestCreateTaskOnlyTakesExistingLabels(t *testing.T) {
+	app := newTestApp(t)
+	category := app.createCategory("Todo")
+	label := models.Label{Name: "bug"}
+	app.db.Create(&label)
+	token := app.tokenFor(app.createUser("member@example.com", policy.RoleMember))
+
+	rec := app.do(http.MethodPost, "/tasks", map[string]interface{}{"title": "Unknown label", "category_id": category.ID, "label_ids": []uint{label.ID + 1}}, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown label got %d, want 400", rec.Code)
+	}
+
+	rec = app.do(http.MethodPost, "/tasks", map[string]interface{}{"title": "Known label", "category_id": category.ID, "label_ids": []uint{label.ID}}, token)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("existing label got %d: %s", rec.Code, rec.Body.String())
+	}
+	var task models.Task
+	app.db.Preload("Labels").Where("title = ?", "Known label").First(&task)
+	if len(task.Labels) != 1 || task.Labels[0].ID != label.ID {
+		t.Errorf("task labels are %+v", task.Labels)
+	}
+}
+
+func TestCreateTaskInSprintNeedsSprintManage(t *testing.T) {
+	app := newTestApp(t)
+	category := app.createCategory("Todo")
+	sprint := models.Sprint{Name: "Sprint 1"}
+	app.db.Create(&sprint)
+	memberToken := app.tokenFor(app.createUser("member@example.com", policy.RoleMember))
+	adminToken := app.tokenFor(app.createUser("admin@example.com", policy.RoleAdmin))
+
+	body := map[string]interface{}{"title": "Planned", "category_id": category.ID, "sprint_id": sprint.ID}
+	if rec := app.do(http.MethodPost, "/tasks", body, memberToken); rec.Code != http.StatusUnauthorized {
+		t.Errorf("member planning a task got %d, want 401", rec.Code)
+	}
+	if rec := app.do(http.MethodPost, "/tasks", body, adminToken); rec.Code != http.StatusCreated {
+		t.Errorf("sprint manager planning a task got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateTaskFromTemplateKeepsLabelsSent(t *testing.T) {
+	app := newTestApp(t)
+	category := app.createCategory("Todo")
+	bug, feature := models.Label{Name: "bug"}, models.Label{Name: "feature"}
+	app.db.Create(&bug)
+	app.db.Create(&feature)
+	template := models.TaskTemplate{Name: "Bug report", CategoryID: &category.ID, Labels: []models.Label{bug}}
+	app.db.Create(&template)
+	token := app.tokenFor(app.createUser("member@example.com", policy.RoleMember))
+
+	tests := []struct {
+		title    string
+		labelIDs []uint
+		want     uint
+	}{
+		{"Template labels", nil, bug.ID},
+		{"Labels sent", []uint{feature.ID}, feature.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.title, func(t *testing.T) {
+			rec := app.do(http.MethodPost, fmt.Sprintf("/tasks?template=%d", template.ID), map[string]interface{}{"title": tt.title, "label_ids": tt.labelIDs}, token)
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("create task returned %d: %s", rec.Code, rec.Body.String())
+			}
+			var task models.Task
+			app.db.Preload("Labels").Where("title = ?", tt.title).First(&task)
+			if len(task.Labels) != 1 || task.Labels[0].ID != tt.want {
+				t.Errorf("task labels are %+v, want label %d", task.Labels, tt.want)
+			}
+		})
+	}
+}
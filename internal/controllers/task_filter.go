@@ -0,0 +1,165 @@
+package controllers
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// taskSorts maps the sort values of a task filter to their ORDER BY
+var taskSorts = map[string]string{
+	"created_at":  "tasks.created_at, tasks.id",
+	"-created_at": "tasks.created_at DESC, tasks.id DESC",
+	"updated_at":  "tasks.updated_at, tasks.id",
+	"-updated_at": "tasks.updated_at DESC, tasks.id DESC",
+	"due_date":    "tasks.due_date IS NULL, tasks.due_date, tasks.id",
+	"-due_date":   "tasks.due_date IS NULL, tasks.due_date DESC, tasks.id",
+	"title":       "LOWER(tasks.title), tasks.id",
+	"-title":      "LOWER(tasks.title) DESC, tasks.id",
+}
+
+// parseTaskFilter reads the filter query parameters of GET /tasks on top of
+// filter: status, category_id, label_id, sprint_id (comma separated lists),
+// assignee_id ("none" for unassigned tasks), due_from, due_to and sort
+func parseTaskFilter(query url.Values, filter models.TaskFilter) (models.TaskFilter, error) {
+	if value := query.Get("status"); value != "" {
+		status, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("status must be true or false")
+		}
+		filter.Status = &status
+	}
+
+	if value := query.Get("category_id"); value != "" {
+		ids, err := parseIDList(value)
+		if err != nil {
+			return filter, errors.New("Invalid category ID")
+		}
+		filter.CategoryIDs = ids
+	}
+
+	if value := query.Get("label_id"); value != "" {
+		ids, err := parseIDList(value)
+		if err != nil {
+			return filter, errors.New("Invalid label ID")
+		}
+		filter.LabelIDs = ids
+	}
+
+	if value := query.Get("sprint_id"); value != "" {
+		ids, err := parseIDList(value)
+		if err != nil {
+			return filter, errors.New("Invalid sprint ID")
+		}
+		filter.SprintIDs = ids
+	}
+
+	if value := query.Get("assignee_id"); value == "none" {
+		filter.AssigneeID = nil
+		filter.Unassigned = true
+	} else if value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("Invalid assignee ID")
+		}
+		assigneeID := uint(id)
+		filter.AssigneeID = &assigneeID
+		filter.Unassigned = false
+	}
+
+	if value := query.Get("due_from"); value != "" {
+		dueFrom, err := parseFilterTime(value, false)
+		if err != nil {
+			return filter, errors.New("due_from must be a date (2006-01-02) or an RFC 3339 time")
+		}
+		filter.DueFrom = &dueFrom
+	}
+	if value := query.Get("due_to"); value != "" {
+		dueTo, err := parseFilterTime(value, true)
+		if err != nil {
+			return filter, errors.New("due_to must be a date (2006-01-02) or an RFC 3339 time")
+		}
+		filter.DueTo = &dueTo
+	}
+
+	if value := query.Get("sort"); value != "" {
+		filter.Sort = value
+	}
+	if err := validateTaskFilter(filter); err != nil {
+		return filter, err
+	}
+
+	return filter, nil
+}
+
+func validateTaskFilter(filter models.TaskFilter) error {
+	if _, ok := taskSorts[filter.Sort]; filter.Sort != "" && !ok {
+		return errors.New("sort must be created_at, updated_at, due_date or title, with a leading - for descending order")
+	}
+	if filter.DueFrom != nil && filter.DueTo != nil && filter.DueTo.Before(*filter.DueFrom) {
+		return errors.New("due_to must not be before due_from")
+	}
+	return nil
+}
+
+func parseIDList(value string) ([]uint, error) {
+	var ids []uint
+	for _, part := range strings.Split(value, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			return nil, errors.New("invalid ID")
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// parseFilterTime reads an RFC 3339 time or a date. A date used as the end
+// of a range includes the whole day.
+func parseFilterTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err == nil && endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, err
+}
+
+// applyTaskFilter adds the conditions and order of a filter to a task query
+func applyTaskFilter(query *gorm.DB, filter models.TaskFilter) *gorm.DB {
+	if filter.Status != nil {
+		query = query.Where("tasks.status = ?", *filter.Status)
+	}
+	if len(filter.CategoryIDs) > 0 {
+		query = query.Where("tasks.category_id IN ?", filter.CategoryIDs)
+	}
+	if len(filter.LabelIDs) > 0 {
+		query = query.Where("tasks.id IN (SELECT task_id FROM task_labels WHERE label_id IN ?)", filter.LabelIDs)
+	}
+	if len(filter.SprintIDs) > 0 {
+		query = query.Where("tasks.sprint_id IN ?", filter.SprintIDs)
+	}
+	if filter.Unassigned {
+		query = query.Where("tasks.assignee_id IS NULL")
+	} else if filter.AssigneeID != nil {
+		query = query.Where("tasks.assignee_id = ?", *filter.AssigneeID)
+	}
+	if filter.DueFrom != nil {
+		query = query.Where("tasks.due_date >= ?", *filter.DueFrom)
+	}
+	if filter.DueTo != nil {
+		query = query.Where("tasks.due_date <= ?", *filter.DueTo)
+	}
+
+	if order, ok := taskSorts[filter.Sort]; ok {
+		return query.Order(order)
+	}
+	return query.Order("tasks.id")
+}
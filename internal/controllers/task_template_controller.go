@@ -0,0 +1,262 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type taskTemplateRequest struct {
+	Name         string                         `json:"name"`
+	TitlePattern string                         `json:"title_pattern"`
+	Description  string                         `json:"description"`
+	CategoryID   *uint                          `json:"category_id"`
+	LabelIDs     []uint                         `json:"label_ids"`
+	Checklist    []models.TemplateChecklistItem `json:"checklist"`
+}
+
+// decodeTaskTemplate reads a template and loads its labels
+func decodeTaskTemplate(w http.ResponseWriter, r *http.Request, db *gorm.DB, template *models.TaskTemplate) ([]models.Label, bool) {
+	var requestBody taskTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	requestBody.Name = strings.TrimSpace(requestBody.Name)
+	if requestBody.Name == "" {
+		http.Error(w, "Template name is required", http.StatusBadRequest)
+		return nil, false
+	}
+	if requestBody.CategoryID != nil {
+		var category models.Category
+		if err := db.First(&category, *requestBody.CategoryID).Error; err != nil {
+			http.Error(w, "Category not found", http.StatusBadRequest)
+			return nil, false
+		}
+	}
+
+	labels, ok := findLabels(w, db, requestBody.LabelIDs)
+	if !ok {
+		return nil, false
+	}
+
+	checklist := []models.TemplateChecklistItem{}
+	for _, item := range requestBody.Checklist {
+		item.Text = strings.TrimSpace(item.Text)
+		if item.Text == "" {
+			http.Error(w, "Checklist items need a text", http.StatusBadRequest)
+			return nil, false
+		}
+		checklist = append(checklist, item)
+	}
+
+	template.Name = requestBody.Name
+	template.TitlePattern = requestBody.TitlePattern
+	template.Description = requestBody.Description
+	template.CategoryID = requestBody.CategoryID
+	template.Checklist = checklist
+	return labels, true
+}
+
+func findTaskTemplate(w http.ResponseWriter, db *gorm.DB, templateID string) (models.TaskTemplate, bool) {
+	var template models.TaskTemplate
+	if err := db.Preload("Labels").First(&template, templateID).Error; err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return template, false
+	}
+	return template, true
+}
+
+// applyTaskTemplate fills a task from a template. Fields sent with the task,
+// labels included, override the template, and its title fills {title} in
+// the title pattern.
+func applyTaskTemplate(task *models.Task, template models.TaskTemplate, user models.User, now time.Time) error {
+	if template.TitlePattern != "" {
+		if strings.Contains(template.TitlePattern, "{title}") || task.Title == "" {
+			task.Title = strings.NewReplacer(
+				"{title}", task.Title,
+				"{date}", now.Format("2006-01-02"),
+				"{user}", user.FullName,
+			).Replace(template.TitlePattern)
+		}
+	}
+	task.Title = strings.TrimSpace(task.Title)
+	if task.Title == "" {
+		return errors.New("Title is required")
+	}
+
+	if task.Description == "" {
+		task.Description = template.Description
+	}
+	if task.CategoryID == 0 && template.CategoryID != nil {
+		task.CategoryID = *template.CategoryID
+	}
+	if len(task.Labels) == 0 {
+		task.Labels = template.Labels
+	}
+	return nil
+}
+
+// createTemplateChecklist copies the checklist of a template to a task
+func createTemplateChecklist(tx *gorm.DB, task models.Task, template models.TaskTemplate) error {
+	for position, spec := range template.Checklist {
+		item := models.ChecklistItem{
+			TaskID:    task.ID,
+			Checklist: spec.Checklist,
+			Text:      spec.Text,
+			Position:  position,
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTaskTemplates lists the task templates
+func GetTaskTemplates(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := config.Authenticate(r, db); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		templates := []models.TaskTemplate{}
+		if err := db.Preload("Labels").Order("name").Find(&templates).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, templates)
+	}
+}
+
+// GetTaskTemplate returns a task template
+func GetTaskTemplate(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, err := config.Authenticate(r, db); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		template, ok := findTaskTemplate(w, db, mux.Vars(r)["templateId"])
+		if !ok {
+			return
+		}
+
+		config.SendJSONResponse(w, template)
+	}
+}
+
+// CreateTaskTemplate creates a new task template
+func CreateTaskTemplate(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.CategoryManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var template models.TaskTemplate
+		labels, ok := decodeTaskTemplate(w, r, db, &template)
+		if !ok {
+			return
+		}
+		template.Labels = labels
+
+		var count int64
+		db.Model(&models.TaskTemplate{}).Where("name = ?", template.Name).Count(&count)
+		if count > 0 {
+			http.Error(w, "A template with this name already exists", http.StatusConflict)
+			return
+		}
+
+		if err := db.Create(&template).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, template)
+	}
+}
+
+// UpdateTaskTemplate replaces a task template. Tasks already created from
+// it are left as they are.
+func UpdateTaskTemplate(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.CategoryManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		template, ok := findTaskTemplate(w, db, mux.Vars(r)["templateId"])
+		if !ok {
+			return
+		}
+		labels, ok := decodeTaskTemplate(w, r, db, &template)
+		if !ok {
+			return
+		}
+
+		var count int64
+		db.Model(&models.TaskTemplate{}).Where("name = ? AND id <> ?", template.Name, template.ID).Count(&count)
+		if count > 0 {
+			http.Error(w, "A template with this name already exists", http.StatusConflict)
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Omit("Labels").Save(&template).Error; err != nil {
+				return err
+			}
+			return tx.Model(&template).Association("Labels").Replace(labels)
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		template.Labels = labels
+
+		config.SendJSONResponse(w, template)
+	}
+}
+
+// DeleteTaskTemplate deletes a task template
+func DeleteTaskTemplate(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.CategoryManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		template, ok := findTaskTemplate(w, db, mux.Vars(r)["templateId"])
+		if !ok {
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&template).Association("Labels").Clear(); err != nil {
+				return err
+			}
+			return tx.Delete(&template).Error
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Template has been successfully deleted"})
+	}
+}
@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/timetracking"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type TimeEntryResponse struct {
+	models.TimeEntry
+	Seconds int64 `json:"seconds"`
+}
+
+func newTimeEntryResponse(entry models.TimeEntry) TimeEntryResponse {
+	return TimeEntryResponse{TimeEntry: entry, Seconds: int64(timetracking.Duration(entry, time.Now()).Seconds())}
+}
+
+type timeEntryRequest struct {
+	StartedAt       *time.Time `json:"started_at"`
+	EndedAt         *time.Time `json:"ended_at"`
+	DurationMinutes *int       `json:"duration_minutes"`
+	Note            string     `json:"note"`
+}
+
+// apply sets the times of an entry from either ended_at or duration_minutes
+func (requestBody timeEntryRequest) apply(entry *models.TimeEntry) error {
+	if requestBody.StartedAt == nil {
+		return errors.New("started_at is required")
+	}
+	endedAt := requestBody.EndedAt
+	if requestBody.DurationMinutes != nil {
+		if endedAt != nil {
+			return errors.New("Send either ended_at or duration_minutes")
+		}
+		t := requestBody.StartedAt.Add(time.Duration(*requestBody.DurationMinutes) * time.Minute)
+		endedAt = &t
+	}
+	if endedAt == nil {
+		return errors.New("ended_at or duration_minutes is required")
+	}
+	if !endedAt.After(*requestBody.StartedAt) {
+		return errors.New("The entry must end after it starts")
+	}
+	if endedAt.After(time.Now()) {
+		return errors.New("Time cannot be logged in the future")
+	}
+
+	entry.StartedAt = *requestBody.StartedAt
+	entry.EndedAt = endedAt
+	entry.Note = requestBody.Note
+	return nil
+}
+
+// findTrackedTask loads the task of the path that the user may track time on
+func findTrackedTask(w http.ResponseWriter, r *http.Request, db *gorm.DB, actor policy.Actor) (models.Task, bool) {
+	var task models.Task
+
+	taskID, err := strconv.Atoi(mux.Vars(r)["taskId"])
+	if err != nil {
+		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+		return task, false
+	}
+	if err := db.First(&task, taskID).Error; err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return task, false
+	}
+	if !policy.Can(db, actor, policy.TaskRead, task) {
+		http.Error(w, "Unauthorized to track time on this task", http.StatusUnauthorized)
+		return task, false
+	}
+
+	return task, true
+}
+
+// findOwnTimeEntry loads an entry of the user
+func findOwnTimeEntry(w http.ResponseWriter, r *http.Request, db *gorm.DB, userID uint) (models.TimeEntry, bool) {
+	var entry models.TimeEntry
+	if err := db.Where("id = ? AND user_id = ?", mux.Vars(r)["entryId"], userID).First(&entry).Error; err != nil {
+		http.Error(w, "Time entry not found", http.StatusNotFound)
+		return entry, false
+	}
+	return entry, true
+}
+
+// StartTimer starts a timer on a task, stopping the one running on another
+// task
+func StartTimer(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		task, ok := findTrackedTask(w, r, db, actor)
+		if !ok {
+			return
+		}
+
+		var requestBody struct {
+			Note string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		entry, err := timetracking.Start(db, claims.UserID, task.ID, requestBody.Note)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, newTimeEntryResponse(*entry))
+	}
+}
+
+// StopTimer stops the timer of the user on a task
+func StopTimer(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		task, ok := findTrackedTask(w, r, db, actor)
+		if !ok {
+			return
+		}
+
+		entry, err := timetracking.Stop(db, claims.UserID, task.ID)
+		if errors.Is(err, timetracking.ErrNoTimer) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, newTimeEntryResponse(*entry))
+	}
+}
+
+// CreateTimeEntry logs time spent on a task
+func CreateTimeEntry(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		task, ok := findTrackedTask(w, r, db, actor)
+		if !ok {
+			return
+		}
+
+		var requestBody timeEntryRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		entry := models.TimeEntry{TaskID: task.ID, UserID: claims.UserID}
+		if err := requestBody.apply(&entry); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := db.Create(&entry).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		config.SendJSONResponse(w, newTimeEntryResponse(entry))
+	}
+}
+
+// GetTimeEntries lists the time entries of a task, latest first
+func GetTimeEntries(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		task, ok := findTrackedTask(w, r, db, actor)
+		if !ok {
+			return
+		}
+
+		var entries []models.TimeEntry
+		if err := db.Where("task_id = ?", task.ID).Order("started_at DESC, id DESC").Find(&entries).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := []TimeEntryResponse{}
+		for _, entry := range entries {
+			response = append(response, newTimeEntryResponse(entry))
+		}
+
+		config.SendJSONResponse(w, response)
+	}
+}
+
+// UpdateTimeEntry replaces the times and note of an entry of the user
+func UpdateTimeEntry(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		entry, ok := findOwnTimeEntry(w, r, db, claims.UserID)
+		if !ok {
+			return
+		}
+		if entry.EndedAt == nil {
+			http.Error(w, "Stop the timer before changing the entry", http.StatusConflict)
+			return
+		}
+
+		var requestBody timeEntryRequest
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := requestBody.apply(&entry); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := db.Save(&entry).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, newTimeEntryResponse(entry))
+	}
+}
+
+// DeleteTimeEntry deletes an entry of the user
+func DeleteTimeEntry(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, err := config.Authenticate(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		entry, ok := findOwnTimeEntry(w, r, db, claims.UserID)
+		if !ok {
+			return
+		}
+
+		if err := db.Delete(&entry).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Time entry has been successfully deleted"})
+	}
+}
+
+// GetTimeReport sums up time by user, category or date between from and to
+// (the last 30 days by default), as JSON or with format=csv as a CSV file.
+// Users who can read any task see everyone's time, others only their own.
+func GetTimeReport(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		from, to, ok := parseAnalyticsRange(w, r)
+		if !ok {
+			return
+		}
+		query := timetracking.ReportQuery{
+			UserID:   claims.UserID,
+			AllUsers: policy.Has(db, actor, policy.TaskReadAny),
+			From:     from,
+			To:       to,
+			GroupBy:  r.URL.Query().Get("group_by"),
+		}
+		if query.GroupBy == "" {
+			query.GroupBy = timetracking.GroupByUser
+		}
+		if !timetracking.ValidGroupBy(query.GroupBy) {
+			http.Error(w, "group_by must be user, category or date", http.StatusBadRequest)
+			return
+		}
+
+		if value := r.URL.Query().Get("user_id"); value != "" {
+			userID, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+			if uint(userID) != claims.UserID && !query.AllUsers {
+				http.Error(w, "Unauthorized to view the time of other users", http.StatusUnauthorized)
+				return
+			}
+			query.UserID = uint(userID)
+			query.AllUsers = false
+		}
+		if value := r.URL.Query().Get("category_id"); value != "" {
+			categoryID, err := strconv.Atoi(value)
+			if err != nil {
+				http.Error(w, "Invalid category ID", http.StatusBadRequest)
+				return
+			}
+			id := uint(categoryID)
+			query.CategoryID = &id
+		}
+
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "csv" {
+			http.Error(w, "format must be json or csv", http.StatusBadRequest)
+			return
+		}
+
+		report, err := timetracking.BuildReport(db, query)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", `attachment; filename="time-report.csv"`)
+			if err := timetracking.WriteCSV(w, report); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+
+		config.SendJSONResponse(w, report)
+	}
+}
@@ -0,0 +1,51 @@
+package controllers_test
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+)
+
+func TestParallelTimersLeaveOneRunning(t *testing.T) {
+	app := newTestApp(t)
+	category := app.createCategory("Todo")
+	user := app.createUser("member@example.com", policy.RoleMember)
+	token := app.tokenFor(user)
+
+	var tasks []models.Task
+	for i := 0; i < 5; i++ {
+		task := models.Task{Title: fmt.Sprintf("Task %d", i), CategoryID: category.ID, UserID: user.ID}
+		if err := app.db.Create(&task).Error; err != nil {
+			t.Fatal(err)
+		}
+		tasks = append(tasks, task)
+	}
+
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func(task models.Task) {
+			defer wg.Done()
+			if rec := app.do(http.MethodPost, fmt.Sprintf("/tasks/%d/timer/start", task.ID), nil, token); rec.Code != http.StatusCreated {
+				t.Errorf("start timer returned %d: %s", rec.Code, rec.Body.String())
+			}
+		}(task)
+	}
+	wg.Wait()
+
+	var running int64
+	app.db.Model(&models.TimeEntry{}).Where("user_id = ? AND ended_at IS NULL", user.ID).Count(&running)
+	if running != 1 {
+		t.Fatalf("%d timers are running, want 1", running)
+	}
+
+	second := models.TimeEntry{TaskID: tasks[0].ID, UserID: user.ID, StartedAt: time.Now()}
+	if err := app.db.Create(&second).Error; err == nil {
+		t.Error("the database accepted a second running timer")
+	}
+}
@@ -0,0 +1,430 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/totp"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const recoveryCodeCount = 10
+
+// completeLogin finishes a login after the first factor. Users with
+// two-factor authentication get a challenge instead of a token. attempt is
+// the one started by beginLoginAttempt, or nil when there is none.
+func completeLogin(w http.ResponseWriter, r *http.Request, db *gorm.DB, user models.User, method string, attempt *models.LoginAttempt) {
+	if err := config.AccountBlocked(user); err != nil {
+		if attempt != nil {
+			cancelLoginAttempt(db, attempt)
+		}
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		if attempt != nil {
+			finishLoginAttempt(db, attempt, &user, models.LoginSucceeded, method)
+		} else {
+			recordLoginAttempt(db, config.NormalizeEmail(user.Email), &user, config.ClientIP(r), models.LoginSucceeded, method)
+		}
+		sendLoginResponse(w, db, user)
+		return
+	}
+
+	// The login only counts once the second factor is checked
+	if attempt != nil {
+		cancelLoginAttempt(db, attempt)
+	}
+
+	challenge, err := config.IssueTwoFactorChallenge(user)
+	if err != nil {
+		http.Error(w, "Error while signing the token", http.StatusInternalServerError)
+		return
+	}
+
+	config.SendJSONResponse(w, map[string]interface{}{
+		"two_factor_required": true,
+		"challenge_token":     challenge,
+	})
+}
+
+// verifyTOTP checks a code from the authenticator app. A code is accepted
+// only once, even within its time window.
+func verifyTOTP(db *gorm.DB, user models.User, code string) bool {
+	step, ok := totp.Validate(user.TwoFactorSecret, code, time.Now())
+	if !ok {
+		return false
+	}
+
+	result := db.Model(&models.User{}).
+		Where("id = ? AND two_factor_last_step < ?", user.ID, step).
+		Update("two_factor_last_step", step)
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// useRecoveryCode marks a recovery code as used if it belongs to the user
+func useRecoveryCode(db *gorm.DB, user models.User, code string) bool {
+	result := db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, config.HashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	return result.Error == nil && result.RowsAffected == 1
+}
+
+// verifySecondFactor accepts either a code from the authenticator app or a
+// recovery code
+func verifySecondFactor(db *gorm.DB, user models.User, code, recoveryCode string) bool {
+	if code != "" {
+		return verifyTOTP(db, user, code)
+	}
+	if recoveryCode != "" {
+		return useRecoveryCode(db, user, recoveryCode)
+	}
+	return false
+}
+
+// replaceRecoveryCodes throws away the old recovery codes and returns new ones
+func replaceRecoveryCodes(db *gorm.DB, userID uint) ([]string, error) {
+	codes, err := config.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		for _, code := range codes {
+			if err := tx.Create(&models.RecoveryCode{UserID: userID, CodeHash: config.HashRecoveryCode(code)}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return codes, err
+}
+
+// LoginTwoFactor trades the challenge from LoginUser and a code for a token
+func LoginTwoFactor(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestBody struct {
+			ChallengeToken string `json:"challenge_token"`
+			Code           string `json:"code"`
+			RecoveryCode   string `json:"recovery_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		userID, err := config.ParseTwoFactorChallenge(requestBody.ChallengeToken)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, userID).Error; err != nil || !user.TwoFactorEnabled {
+			http.Error(w, "Invalid or expired challenge token", http.StatusUnauthorized)
+			return
+		}
+		if err := config.AccountBlocked(user); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		// Wrong codes count towards the lockout like wrong passwords
+		email := config.NormalizeEmail(user.Email)
+		ip := config.ClientIP(r)
+		attempt, wait, err := beginLoginAttempt(db, email, ip)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if wait > 0 {
+			sendTooManyAttempts(w, wait)
+			return
+		}
+
+		if !verifySecondFactor(db, user, requestBody.Code, requestBody.RecoveryCode) {
+			finishLoginAttempt(db, &attempt, &user, models.LoginFailed, "wrong two-factor code")
+			http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+
+		finishLoginAttempt(db, &attempt, &user, models.LoginSucceeded, "two_factor")
+		sendLoginResponse(w, db, user)
+	}
+}
+
+// SetupTwoFactor creates a new secret for the authenticator app. It is only
+// used after EnableTwoFactor confirms a code from it.
+func SetupTwoFactor(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if user.TwoFactorEnabled {
+			http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+
+		secret, err := totp.GenerateSecret()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := db.Model(&user).Update("two_factor_secret", secret).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{
+			"secret":      secret,
+			"otpauth_uri": totp.URI(config.TwoFactorIssuer, user.Email, secret),
+		})
+	}
+}
+
+// EnableTwoFactor turns on two-factor authentication once the user proves
+// the authenticator app works. The recovery codes are only shown here.
+func EnableTwoFactor(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		var requestBody struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if user.TwoFactorEnabled {
+			http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+			return
+		}
+		if user.TwoFactorSecret == "" {
+			http.Error(w, "Call "+config.TwoFactorPathPrefix+"setup first", http.StatusBadRequest)
+			return
+		}
+
+		if !verifyTOTP(db, user, requestBody.Code) {
+			http.Error(w, "Invalid two-factor code", http.StatusBadRequest)
+			return
+		}
+
+		codes, err := replaceRecoveryCodes(db, user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := db.Model(&user).Update("two_factor_enabled", true).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]interface{}{
+			"message":        "Two-factor authentication has been enabled",
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// DisableTwoFactor turns off two-factor authentication. It needs the password
+// and a code, so a stolen session alone cannot do it.
+func DisableTwoFactor(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		var requestBody struct {
+			Password     string `json:"password"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if !user.TwoFactorEnabled {
+			http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+			return
+		}
+		if policy.IsPrivileged(db, user.Role) && config.RequireAdminTwoFactor(db) {
+			http.Error(w, "Two-factor authentication is required for admins", http.StatusForbidden)
+			return
+		}
+
+		// Wrong passwords and codes count towards the lockout like on login
+		attempt, wait, err := beginLoginAttempt(db, config.NormalizeEmail(user.Email), config.ClientIP(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if wait > 0 {
+			sendTooManyAttempts(w, wait)
+			return
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.Password)); err != nil {
+			finishLoginAttempt(db, &attempt, &user, models.LoginFailed, "wrong password disabling two-factor")
+			http.Error(w, "Invalid password", http.StatusUnauthorized)
+			return
+		}
+		if !verifySecondFactor(db, user, requestBody.Code, requestBody.RecoveryCode) {
+			finishLoginAttempt(db, &attempt, &user, models.LoginFailed, "wrong two-factor code disabling two-factor")
+			http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+		cancelLoginAttempt(db, &attempt)
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&user).Updates(map[string]interface{}{
+				"two_factor_enabled":   false,
+				"two_factor_secret":    "",
+				"two_factor_last_step": 0,
+			}).Error
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{"message": "Two-factor authentication has been disabled"})
+	}
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes of the current user
+func RegenerateRecoveryCodes(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		var requestBody struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if !user.TwoFactorEnabled {
+			http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+			return
+		}
+		if !verifyTOTP(db, user, requestBody.Code) {
+			http.Error(w, "Invalid two-factor code", http.StatusUnauthorized)
+			return
+		}
+
+		codes, err := replaceRecoveryCodes(db, user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]interface{}{"recovery_codes": codes})
+	}
+}
+
+// GetSecuritySettings shows the security settings admins can change
+func GetSecuritySettings(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authorized, err := config.AuthenticateAndAuthorize(r, db, policy.SettingsManage)
+		if err != nil || !authorized {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]bool{
+			"require_admin_two_factor": config.RequireAdminTwoFactor(db),
+		})
+	}
+}
+
+// UpdateSecuritySettings lets admins require two-factor authentication for
+// every admin. Only an admin that uses it can turn it on.
+func UpdateSecuritySettings(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, actor, err := config.AuthenticateActor(r, db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if !policy.Has(db, actor, policy.SettingsManage) {
+			http.Error(w, "Unauthorized access", http.StatusUnauthorized)
+			return
+		}
+
+		var requestBody struct {
+			RequireAdminTwoFactor bool `json:"require_admin_two_factor"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if requestBody.RequireAdminTwoFactor && !actor.TwoFactorEnabled {
+			http.Error(w, "Enable two-factor authentication on your own account first", http.StatusConflict)
+			return
+		}
+
+		value := "false"
+		if requestBody.RequireAdminTwoFactor {
+			value = "true"
+		}
+		if err := config.SetSetting(db, models.SettingRequireAdminTwoFactor, value); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]bool{
+			"require_admin_two_factor": requestBody.RequireAdminTwoFactor,
+		})
+	}
+}
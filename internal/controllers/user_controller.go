@@ -2,14 +2,19 @@ package controllers
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"strings"
+	"net/url"
+	"strconv"
 	"time"
 
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/accounts"
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
-	"github.com/golang-jwt/jwt/v4"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/notifications"
+	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -28,6 +33,15 @@ func RegisterUser(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		if err := config.ValidateEmail(requestBody.Email); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := config.Passwords.Validate(requestBody.Password); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(requestBody.Password), bcrypt.DefaultCost)
 		if err != nil {
 			http.Error(w, "Error while hashing password", http.StatusInternalServerError)
@@ -37,7 +51,7 @@ func RegisterUser(db *gorm.DB) http.HandlerFunc {
 		// User role is hardcoded as 'member'
 		user := models.User{
 			FullName: requestBody.FullName,
-			Email:    requestBody.Email,
+			Email:    config.NormalizeEmail(requestBody.Email),
 			Password: string(hashedPassword),
 			Role:     "member",
 		}
@@ -48,6 +62,8 @@ func RegisterUser(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		sendVerificationEmail(db, user)
+
 		w.WriteHeader(http.StatusCreated)
 		config.SendJSONResponse(w, map[string]interface{}{
 			"id":         user.ID,
@@ -58,29 +74,6 @@ func RegisterUser(db *gorm.DB) http.HandlerFunc {
 	}
 }
 
-func InitializeAdminUser(db *gorm.DB) {
-	adminPassword := "admin123"
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(adminPassword), bcrypt.DefaultCost)
-	if err != nil {
-		log.Fatalf("Failed to hash admin password: %v", err)
-	}
-
-	adminUser := models.User{
-		FullName: "Admin User",
-		Email:    "admin@gmail.com",
-		Password: string(hashedPassword),
-		Role:     "admin",
-	}
-
-	// Check if admin exists
-	var count int64
-	db.Model(&models.User{}).Where("email = ?", adminUser.Email).Count(&count)
-	if count == 0 {
-		// Create admin if not exists
-		db.Create(&adminUser)
-	}
-}
-
 // Login User
 func LoginUser(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -94,79 +87,169 @@ func LoginUser(db *gorm.DB) http.HandlerFunc {
 			return
 		}
 
+		email := config.NormalizeEmail(requestBody.Email)
+		ip := config.ClientIP(r)
+		attempt, wait, err := beginLoginAttempt(db, email, ip)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if wait > 0 {
+			sendTooManyAttempts(w, wait)
+			return
+		}
+
 		var user models.User
-		result := db.Where("email = ?", requestBody.Email).First(&user)
+		result := db.Where("LOWER(email) = ?", email).First(&user)
 		if result.Error != nil {
 			if result.Error == gorm.ErrRecordNotFound {
-				http.Error(w, "User not found", http.StatusUnauthorized)
+				bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(requestBody.Password))
+				finishLoginAttempt(db, &attempt, nil, models.LoginFailed, "unknown email")
+				http.Error(w, invalidCredentials, http.StatusUnauthorized)
 			} else {
+				cancelLoginAttempt(db, &attempt)
 				http.Error(w, result.Error.Error(), http.StatusInternalServerError)
 			}
 			return
 		}
 
 		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.Password)); err != nil {
-			http.Error(w, "Invalid password", http.StatusUnauthorized)
+			finishLoginAttempt(db, &attempt, &user, models.LoginFailed, "wrong password")
+			http.Error(w, invalidCredentials, http.StatusUnauthorized)
 			return
 		}
 
-		expirationTime := time.Now().Add(1 * time.Hour)
-		claims := &config.Claims{
-			Email: user.Email,
-			RegisteredClaims: jwt.RegisteredClaims{
-				ExpiresAt: jwt.NewNumericDate(expirationTime),
-			},
-		}
-
-		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		tokenString, err := token.SignedString(config.JwtKey)
-		if err != nil {
-			http.Error(w, "Error while signing the token", http.StatusInternalServerError)
+		if config.RequireEmailVerification && user.EmailVerifiedAt == nil {
+			cancelLoginAttempt(db, &attempt)
+			http.Error(w, "Email address has not been verified", http.StatusForbidden)
 			return
 		}
 
-		config.SendJSONResponse(w, map[string]string{
-			"token": tokenString,
-		})
+		completeLogin(w, r, db, user, "password", &attempt)
+	}
+}
+
+// sendLoginResponse starts a session for a user that has proven who they
+// are and sends back the token for it
+func sendLoginResponse(w http.ResponseWriter, db *gorm.DB, user models.User) {
+	expirationTime := time.Now().Add(1 * time.Hour)
+	session, err := config.CreateSession(db, user.ID, expirationTime)
+	if err != nil {
+		http.Error(w, "Error while creating the session", http.StatusInternalServerError)
+		return
+	}
+
+	claims := &config.Claims{
+		UserID:           user.ID,
+		Email:            user.Email,
+		RegisteredClaims: config.NewRegisteredClaims(session.ID, expirationTime),
+	}
+
+	tokenString, err := config.SignToken(claims)
+	if err != nil {
+		http.Error(w, "Error while signing the token", http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"token": tokenString,
 	}
+	if user.MustChangePassword {
+		response["must_change_password"] = true
+	}
+
+	config.SendJSONResponse(w, response)
 }
 
+// UpdateUserAccount changes the name and email of the user. Changing the
+// email needs the current password, as the email is enough to reset it.
 func UpdateUserAccount(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Mengurai token dari header Authorization
-		authHeader := r.Header.Get("Authorization")
-		authHeaderParts := strings.Split(authHeader, " ")
-		if len(authHeaderParts) != 2 || authHeaderParts[0] != "Bearer" {
-			http.Error(w, "Authorization header must be in the format 'Bearer {token}'", http.StatusUnauthorized)
-			return
-		}
-		tokenString := authHeaderParts[1]
-
-		// Parsing token JWT
-		claims := &config.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return config.JwtKey, nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		// Personal access tokens cannot take over the account
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
 			return
 		}
 
 		// Mendekode request body
 		var requestBody struct {
-			FullName string `json:"full_name"`
-			Email    string `json:"email"`
+			FullName        string `json:"full_name"`
+			Email           string `json:"email"`
+			CurrentPassword string `json:"current_password"`
 		}
-		err = json.NewDecoder(r.Body).Decode(&requestBody)
-		if err != nil {
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		requestBody.Email = config.NormalizeEmail(requestBody.Email)
+		emailChanged := requestBody.Email != "" && requestBody.Email != user.Email
+		if emailChanged {
+			if err := config.ValidateEmail(requestBody.Email); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		// Wrong passwords count towards the lockout like on login
+		if emailChanged {
+			attempt, wait, err := beginLoginAttempt(db, config.NormalizeEmail(user.Email), config.ClientIP(r))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if wait > 0 {
+				sendTooManyAttempts(w, wait)
+				return
+			}
+			if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.CurrentPassword)); err != nil {
+				finishLoginAttempt(db, &attempt, &user, models.LoginFailed, "wrong password changing email")
+				http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
+				return
+			}
+			cancelLoginAttempt(db, &attempt)
+		}
+
+		// The new email address must not belong to another user
+		if emailChanged {
+			var taken int64
+			if err := db.Model(&models.User{}).Where("LOWER(email) = ? AND id <> ?", requestBody.Email, user.ID).Count(&taken).Error; err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if taken > 0 {
+				http.Error(w, "Email is already in use", http.StatusConflict)
+				return
+			}
+		}
+
 		// Memperbarui user
-		user := models.User{}
-		db.Model(&user).Where("email = ?", claims.Email).Updates(models.User{FullName: requestBody.FullName, Email: requestBody.Email})
-		db.Where("email = ?", requestBody.Email).First(&user)
+		updates := models.User{FullName: requestBody.FullName, Email: requestBody.Email}
+		if err := db.Model(&models.User{}).Where("id = ?", user.ID).Updates(updates).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// A new email address has to be verified again
+		if emailChanged {
+			if err := db.Model(&models.User{}).Where("id = ?", user.ID).Update("email_verified_at", nil).Error; err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := db.First(&user, user.ID).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if emailChanged {
+			sendVerificationEmail(db, user)
+		}
 
 		// Mengirimkan respon
 		config.SendJSONResponse(w, map[string]interface{}{
@@ -179,48 +262,456 @@ func UpdateUserAccount(db *gorm.DB) http.HandlerFunc {
 	}
 }
 
+// DeleteUserAccount deactivates the account and schedules it for deletion
+// after the grace period. The user chooses what happens to the tasks they
+// own: transfer them to another user, anonymize them or delete them. A data
+// export is started unless export is false.
 func DeleteUserAccount(db *gorm.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Authenticate the user
-		authHeader := r.Header.Get("Authorization")
-		authHeaderParts := strings.Split(authHeader, " ")
-		if len(authHeaderParts) != 2 || authHeaderParts[0] != "Bearer" {
-			http.Error(w, "Authorization header must be in the format 'Bearer {token}'", http.StatusUnauthorized)
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
 			return
 		}
-		tokenString := authHeaderParts[1]
 
-		claims := &config.Claims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			return config.JwtKey, nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+		var requestBody struct {
+			Tasks      string `json:"tasks"`
+			TransferTo string `json:"transfer_to"`
+			Export     *bool  `json:"export"`
+			IncludeCSV bool   `json:"include_csv"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if isLastAdmin(db, user) {
+			http.Error(w, "Cannot delete the last admin", http.StatusConflict)
 			return
 		}
 
 		// Check if there are any tasks associated with the user
 		var taskCount int64
-		if err := db.Model(&models.Task{}).Where("user_id = ?", claims.UserID).Count(&taskCount).Error; err != nil {
+		if err := db.Model(&models.Task{}).Where("user_id = ?", user.ID).Count(&taskCount).Error; err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		if taskCount > 0 {
-			http.Error(w, "Cannot delete user with associated tasks", http.StatusConflict)
+		action := requestBody.Tasks
+		if action == "" && taskCount == 0 {
+			action = accounts.TasksDelete
+		}
+		if !accounts.ValidTaskAction(action) {
+			http.Error(w, "tasks must be transfer, anonymize or delete", http.StatusBadRequest)
 			return
 		}
 
-		// Delete the user
-		result := db.Where("email = ?", claims.Email).Delete(&models.User{})
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		var transferToID *uint
+		if action == accounts.TasksTransfer {
+			var target models.User
+			if err := db.Where("LOWER(email) = ?", config.NormalizeEmail(requestBody.TransferTo)).First(&target).Error; err != nil {
+				http.Error(w, "User to transfer the tasks to not found", http.StatusBadRequest)
+				return
+			}
+			if target.ID == user.ID || config.AccountBlocked(target) != nil {
+				http.Error(w, "Tasks cannot be transferred to this user", http.StatusBadRequest)
+				return
+			}
+			transferToID = &target.ID
+		}
+
+		// This session stays open for the data export only
+		if err := accounts.ScheduleDeletion(db, &user, action, transferToID, claims.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		response := map[string]interface{}{
+			"message":               "Your account has been deactivated and will be deleted, use the link sent by email to restore it",
+			"deletion_scheduled_at": user.DeletionScheduledAt,
+		}
+
+		// A copy of the data is prepared unless the user declines it
+		var export *models.DataExport
+		if requestBody.Export == nil || *requestBody.Export {
+			var err error
+			if export, err = accounts.RequestExport(db, user, requestBody.IncludeCSV); err != nil {
+				log.Printf("Failed to start the data export of account %d: %v", user.ID, err)
+			} else {
+				response["data_export"] = export
+			}
+		}
+
+		token, err := config.IssueUserToken(db, user, models.TokenPurposeRestoreAccount, accounts.DeletionGracePeriod)
+		if err != nil {
+			log.Printf("Failed to issue restore token: %v", err)
+		} else {
+			notifications.AccountDeletionScheduled(user, config.AppURL+"/restore-account?token="+url.QueryEscape(token), *user.DeletionScheduledAt, export != nil)
+		}
+
+		// Send a JSON response indicating the scheduled deletion
+		config.SendJSONResponse(w, response)
+	}
+}
+
+// RestoreAccount cancels a scheduled deletion using the token sent by
+// DeleteUserAccount
+func RestoreAccount(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestBody struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, err := config.ConsumeUserToken(db, requestBody.Token, models.TokenPurposeRestoreAccount)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if user.DeletionScheduledAt == nil {
+			http.Error(w, "Account is not scheduled for deletion", http.StatusBadRequest)
+			return
+		}
+
+		if err := accounts.Restore(db, user); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{
+			"message": "Your account has been successfully restored",
+		})
+	}
+}
+
+// RequestDataExport starts building an archive of the user's personal data.
+// A download link is emailed when it is ready.
+func RequestDataExport(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		var requestBody struct {
+			IncludeCSV bool `json:"include_csv"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil && err != io.EOF {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		export, err := accounts.RequestExport(db, user, requestBody.IncludeCSV)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		config.SendJSONResponse(w, export)
+	}
+}
+
+// GetDataExport shows the status of an export, with its download link once
+// it is ready
+func GetDataExport(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		var export models.DataExport
+		if err := db.Where("id = ? AND user_id = ?", mux.Vars(r)["exportId"], claims.UserID).First(&export).Error; err != nil {
+			http.Error(w, "Export not found", http.StatusNotFound)
+			return
+		}
+
+		response := map[string]interface{}{"export": export}
+		if export.Status == models.DataExportReady {
+			link, err := accounts.DownloadLink(export)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			response["download_url"] = link
+		}
+
+		config.SendJSONResponse(w, response)
+	}
+}
+
+// DownloadDataExport sends the archive of an export. The token in the link
+// stands in for logging in, so the link can be opened from the email.
+func DownloadDataExport(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		exportID, err := strconv.Atoi(mux.Vars(r)["exportId"])
+		if err != nil {
+			http.Error(w, "Invalid export ID", http.StatusBadRequest)
+			return
+		}
+
+		export, err := accounts.CheckDownloadToken(db, uint(exportID), r.URL.Query().Get("token"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="personal-data-%d.zip"`, export.ID))
+		w.Write(export.Archive)
+	}
+}
+
+func sendVerificationEmail(db *gorm.DB, user models.User) {
+	token, err := config.IssueUserToken(db, user, models.TokenPurposeVerifyEmail, 24*time.Hour)
+	if err != nil {
+		log.Printf("Failed to issue verification token: %v", err)
+		return
+	}
+
+	notifications.AccountEmail(notifications.EventVerifyEmail, user, config.AppURL+"/verify-email?token="+url.QueryEscape(token))
+}
+
+// VerifyEmail marks the email of a user as verified using the token sent by email
+func VerifyEmail(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestBody struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, err := config.ConsumeUserToken(db, requestBody.Token, models.TokenPurposeVerifyEmail)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := db.Model(user).Update("email_verified_at", time.Now()).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{
+			"message": "Your email has been successfully verified",
+		})
+	}
+}
+
+// ResendVerificationEmail sends a new verification link. The response is the
+// same whether or not the email is registered.
+func ResendVerificationEmail(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestBody struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.Where("LOWER(email) = ?", config.NormalizeEmail(requestBody.Email)).First(&user).Error; err == nil && user.EmailVerifiedAt == nil {
+			sendVerificationEmail(db, user)
+		}
+
+		config.SendJSONResponse(w, map[string]string{
+			"message": "If the email is registered and not yet verified, a verification link has been sent",
+		})
+	}
+}
+
+// ForgotPassword emails a password reset link. The response is the same
+// whether or not the email is registered.
+func ForgotPassword(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestBody struct {
+			Email string `json:"email"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.Where("LOWER(email) = ?", config.NormalizeEmail(requestBody.Email)).First(&user).Error; err == nil {
+			token, err := config.IssueUserToken(db, user, models.TokenPurposeResetPassword, 30*time.Minute)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			notifications.AccountEmail(notifications.EventPasswordReset, user, config.AppURL+"/reset-password?token="+url.QueryEscape(token))
+		}
+
+		config.SendJSONResponse(w, map[string]string{
+			"message": "If the email is registered, a password reset link has been sent",
+		})
+	}
+}
+
+// ResetPassword sets a new password using the token sent by ForgotPassword
+func ResetPassword(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var requestBody struct {
+			Token    string `json:"token"`
+			Password string `json:"password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := config.Passwords.Validate(requestBody.Password); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, err := config.ConsumeUserToken(db, requestBody.Token, models.TokenPurposeResetPassword)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(requestBody.Password), bcrypt.DefaultCost)
+		if err != nil {
+			http.Error(w, "Error while hashing password", http.StatusInternalServerError)
+			return
+		}
+
+		// The reset link was received by email, so the address is verified too
+		updates := map[string]interface{}{
+			"password":             string(hashedPassword),
+			"must_change_password": false,
+		}
+		if user.EmailVerifiedAt == nil {
+			updates["email_verified_at"] = time.Now()
+		}
+		if err := db.Model(user).Updates(updates).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		// Log out everywhere, whoever knew the old password is locked out
+		if err := config.RevokeSessions(db, user.ID, ""); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := config.RevokePersonalTokens(db, user.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := config.DeleteCalendarFeed(db, user.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		config.SendJSONResponse(w, map[string]string{
+			"message": "Your password has been successfully reset",
+		})
+	}
+}
+
+// ChangePassword sets a new password after checking the current one. Every
+// other session of the user is logged out, their personal access tokens are
+// revoked and their calendar feed is turned off.
+func ChangePassword(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := authenticateSession(w, r, db)
+		if !ok {
+			return
+		}
+
+		var requestBody struct {
+			CurrentPassword string `json:"current_password"`
+			NewPassword     string `json:"new_password"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var user models.User
+		if err := db.First(&user, claims.UserID).Error; err != nil {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		// Wrong passwords count towards the lockout like on login
+		attempt, wait, err := beginLoginAttempt(db, config.NormalizeEmail(user.Email), config.ClientIP(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if wait > 0 {
+			sendTooManyAttempts(w, wait)
+			return
+		}
+
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(requestBody.CurrentPassword)); err != nil {
+			finishLoginAttempt(db, &attempt, &user, models.LoginFailed, "wrong password changing password")
+			http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
+			return
+		}
+		cancelLoginAttempt(db, &attempt)
+
+		if requestBody.NewPassword == requestBody.CurrentPassword {
+			http.Error(w, "New password must be different from the current password", http.StatusBadRequest)
+			return
+		}
+
+		if err := config.Passwords.Validate(requestBody.NewPassword); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(requestBody.NewPassword), bcrypt.DefaultCost)
+		if err != nil {
+			http.Error(w, "Error while hashing password", http.StatusInternalServerError)
+			return
+		}
+
+		if err := db.Model(&user).Updates(map[string]interface{}{
+			"password":             string(hashedPassword),
+			"must_change_password": false,
+		}).Error; err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if err := config.RevokeSessions(db, user.ID, claims.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := config.RevokePersonalTokens(db, user.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := config.DeleteCalendarFeed(db, user.ID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// Send a JSON response indicating successful deletion
 		config.SendJSONResponse(w, map[string]string{
-			"message": "Your account has been successfully deleted",
+			"message": "Your password has been successfully changed",
 		})
 	}
 }
@@ -0,0 +1,89 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/accounts"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/policy"
+)
+
+// waitForExports waits until the background export builds are done, so the
+// database is not closed under them
+func waitForExports(t *testing.T, app *testApp) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		var unfinished int64
+		app.db.Model(&models.DataExport{}).
+			Where("status IN ?", []string{models.DataExportPending, models.DataExportBuilding}).
+			Count(&unfinished)
+		if unfinished == 0 {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatal("data exports were not finished in time")
+}
+
+func TestDeleteAccountStartsDataExport(t *testing.T) {
+	app := newTestApp(t)
+	app.createUser("admin@example.com", policy.RoleAdmin)
+	token := app.tokenFor(app.createUser("leaving@example.com", policy.RoleMember))
+	defer waitForExports(t, app)
+
+	rec := app.do(http.MethodDelete, "/users/delete-account", nil, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete account returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		DataExport *models.DataExport `json:"data_export"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.DataExport == nil {
+		t.Fatalf("delete account did not start a data export: %s", rec.Body.String())
+	}
+
+	// The session can still reach the export, but nothing else
+	if rec := app.do(http.MethodPost, "/users/export", nil, token); rec.Code != http.StatusAccepted {
+		t.Errorf("export while deletion is pending returned %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := app.do(http.MethodGet, "/tasks", nil, token); rec.Code != http.StatusUnauthorized {
+		t.Errorf("tasks while deletion is pending returned %d, want 401", rec.Code)
+	}
+}
+
+func TestPlaceholderAddressIsReserved(t *testing.T) {
+	app := newTestApp(t)
+	rec := app.do(http.MethodPost, "/users/register", map[string]string{"full_name": "Squatter", "email": accounts.DeletedUserEmail, "password": "A-long-password-1"}, "")
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("register with the placeholder address returned %d, want 400", rec.Code)
+	}
+
+	token := app.tokenFor(app.createUser("member@example.com", policy.RoleMember))
+	rec = app.do(http.MethodPut, "/users/update-account", map[string]string{"email": "someone@users.invalid", "current_password": "password"}, token)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("update to an .invalid address returned %d, want 400", rec.Code)
+	}
+
+	// A user who took the address before it was refused does not become the
+	// placeholder
+	squatter := app.createUser(accounts.DeletedUserEmail, policy.RoleMember)
+	leaving := app.createUser("leaving@example.com", policy.RoleMember)
+	category := app.createCategory("Work")
+	task := models.Task{Title: "Task", CategoryID: category.ID, UserID: leaving.ID}
+	app.db.Create(&task)
+	leaving.DeletionTaskAction = accounts.TasksAnonymize
+	if err := accounts.Purge(app.db, leaving); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+
+	var owner models.User
+	app.db.Table("users").Joins("JOIN tasks ON tasks.user_id = users.id").Where("tasks.id = ?", task.ID).First(&owner)
+	if owner.ID == squatter.ID || !owner.Placeholder {
+		t.Errorf("anonymized task went to user %d (%s), want the placeholder", owner.ID, owner.Email)
+	}
+}
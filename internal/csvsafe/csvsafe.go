@@ -0,0 +1,70 @@
+// Package csvsafe writes CSV files that can be opened in a spreadsheet
+// without running cells as formulas.
+package csvsafe
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+)
+
+// formulaPrefixes start a cell that spreadsheets read as a formula
+const formulaPrefixes = "=+-@\t\r"
+
+// Writer is a csv.Writer that escapes every cell it writes
+type Writer struct {
+	*csv.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{Writer: csv.NewWriter(w)}
+}
+
+// Write writes one record with its cells escaped
+func (w *Writer) Write(record []string) error {
+	escaped := make([]string, len(record))
+	for i, cell := range record {
+		escaped[i] = Escape(cell)
+	}
+	return w.Writer.Write(escaped)
+}
+
+// WriteAll writes the records with their cells escaped and flushes
+func (w *Writer) WriteAll(records [][]string) error {
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// Escape prefixes a cell that would be read as a formula with a quote, so
+// spreadsheets show it as text
+func Escape(cell string) string {
+	if needsEscape(cell) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// Unescape undoes Escape, so exported files can be imported again
+func Unescape(cell string) string {
+	if strings.HasPrefix(cell, "'") && needsEscape(cell[1:]) {
+		return cell[1:]
+	}
+	return cell
+}
+
+// needsEscape reports whether a cell starts like a formula. A cell that
+// looks escaped already is escaped again, so Unescape gives it back as is.
+func needsEscape(cell string) bool {
+	if cell == "" {
+		return false
+	}
+	if cell[0] == '\'' {
+		return needsEscape(cell[1:])
+	}
+	return strings.ContainsRune(formulaPrefixes, rune(cell[0]))
+}
@@ -0,0 +1,39 @@
+package csvsafe
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEscape(t *testing.T) {
+	tests := map[string]string{
+		"":                   "",
+		"Plain title":        "Plain title",
+		"=HYPERLINK(\"x\")":  "'=HYPERLINK(\"x\")",
+		"+1":                 "'+1",
+		"-2":                 "'-2",
+		"@SUM(A1)":           "'@SUM(A1)",
+		"\t=1":               "'\t=1",
+		"a=b":                "a=b",
+		"'=already escaped'": "''=already escaped'",
+		"'tis":               "'tis",
+	}
+	for cell, want := range tests {
+		if got := Escape(cell); got != want {
+			t.Errorf("Escape(%q) = %q, want %q", cell, got, want)
+		}
+		if got := Unescape(Escape(cell)); got != cell {
+			t.Errorf("Unescape(Escape(%q)) = %q", cell, got)
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewWriter(&buf).WriteAll([][]string{{"title", "hours"}, {"=cmd", "1.50"}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "title,hours\n'=cmd,1.50\n"; buf.String() != want {
+		t.Errorf("wrote %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CalendarFeed is the private iCalendar feed of a user's due tasks. Only the
+// SHA-256 hash of its token is stored.
+type CalendarFeed struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"uniqueIndex" json:"user_id"`
+	TokenHash     string     `gorm:"uniqueIndex;not null" json:"-"`
+	LastFetchedAt *time.Time `json:"last_fetched_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	User          User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type Comment struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TaskID    uint      `gorm:"index" json:"task_id"`
+	UserID    uint      `gorm:"index" json:"user_id"`
+	Body      string    `gorm:"not null" json:"body" validate:"required"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Task      Task      `gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE;" json:"-"`
+	User      User      `gorm:"foreignKey:UserID" json:"user"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	DataExportPending  = "pending"
+	DataExportBuilding = "building"
+	DataExportReady    = "ready"
+	DataExportFailed   = "failed"
+)
+
+// DataExport is an archive of a user's personal data, built in the
+// background and downloadable until it expires.
+type DataExport struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"index" json:"user_id"`
+	IncludeCSV  bool       `gorm:"not null;default:false" json:"include_csv"`
+	Status      string     `gorm:"not null" json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Archive     []byte     `json:"-"`
+	StartedAt   *time.Time `json:"started_at"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	User        User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
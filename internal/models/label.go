@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Label tags tasks. Labels are shared by every board like categories.
+type Label struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_label_name_color" json:"name"`
+	Color     string    `gorm:"uniqueIndex:idx_label_name_color" json:"color"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ChecklistItem is one item of a checklist on a task. Items of the same
+// checklist share its name.
+type ChecklistItem struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	TaskID    uint      `gorm:"index" json:"task_id"`
+	Checklist string    `json:"checklist"`
+	Text      string    `gorm:"not null" json:"text"`
+	Done      bool      `gorm:"not null;default:false" json:"done"`
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Task      Task      `gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE;" json:"-"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	LoginFailed    = "failed"
+	LoginSucceeded = "succeeded"
+	LoginUnlocked  = "unlocked"
+)
+
+// LoginAttempt is the audit record of a login. Failed attempts since the
+// last success or unlock drive the lockout of an email address.
+type LoginAttempt struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Email     string    `gorm:"index" json:"email"`
+	UserID    *uint     `json:"user_id"`
+	IP        string    `gorm:"index" json:"ip"`
+	Result    string    `gorm:"not null" json:"result"`
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// NotificationPreference stores which emails a user wants to receive. Users
+// without a row get every notification.
+type NotificationPreference struct {
+	ID             uint      `gorm:"primaryKey" json:"-"`
+	UserID         uint      `gorm:"uniqueIndex" json:"user_id"`
+	EmailOnAssign  bool      `json:"email_on_assign"`
+	EmailOnDueSoon bool      `json:"email_on_due_soon"`
+	EmailOnComment bool      `json:"email_on_comment"`
+	CreatedAt      time.Time `json:"-"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	User           User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+// DefaultNotificationPreference returns the preferences used for users that
+// have not saved any.
+func DefaultNotificationPreference(userID uint) NotificationPreference {
+	return NotificationPreference{
+		UserID:         userID,
+		EmailOnAssign:  true,
+		EmailOnDueSoon: true,
+		EmailOnComment: true,
+	}
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// RecurringTask is the template of a series of tasks, created again
+// following its recurrence rule. With OnCompletion the next task is only
+// created once the previous one is done.
+type RecurringTask struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"index" json:"user_id"`
+	Title        string     `gorm:"not null" json:"title"`
+	Description  string     `json:"description"`
+	CategoryID   uint       `json:"category_id"`
+	AssigneeID   *uint      `json:"assignee_id"`
+	Rule         string     `gorm:"not null" json:"rule"`
+	StartAt      time.Time  `gorm:"not null" json:"start_at"`
+	OnCompletion bool       `gorm:"not null;default:false" json:"on_completion"`
+	Paused       bool       `gorm:"not null;default:false" json:"paused"`
+	NextAt       *time.Time `gorm:"index" json:"next_at"`
+	Occurrences  int        `gorm:"not null;default:0" json:"occurrences"`
+	LastTaskID   *uint      `json:"last_task_id"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	User         User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+	Category     Category   `gorm:"foreignKey:CategoryID;constraint:OnDelete:CASCADE;" json:"-"`
+	Assignee     *User      `gorm:"foreignKey:AssigneeID;constraint:OnDelete:SET NULL;" json:"-"`
+}
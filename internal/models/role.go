@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Role is a custom role defined by an admin. The built-in admin and member
+// roles are not stored.
+type Role struct {
+	ID          uint             `gorm:"primaryKey" json:"id"`
+	Name        string           `gorm:"uniqueIndex;not null" json:"name"`
+	Description string           `json:"description"`
+	Permissions []RolePermission `gorm:"foreignKey:RoleID;constraint:OnDelete:CASCADE;" json:"-"`
+	CreatedAt   time.Time        `json:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at"`
+}
+
+type RolePermission struct {
+	ID         uint   `gorm:"primaryKey"`
+	RoleID     uint   `gorm:"uniqueIndex:idx_role_permission"`
+	Permission string `gorm:"not null;uniqueIndex:idx_role_permission"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// TaskFilter narrows and orders the tasks listed by GET /tasks
+type TaskFilter struct {
+	Status      *bool      `json:"status,omitempty"`
+	CategoryIDs []uint     `json:"category_ids,omitempty"`
+	LabelIDs    []uint     `json:"label_ids,omitempty"`
+	SprintIDs   []uint     `json:"sprint_ids,omitempty"`
+	AssigneeID  *uint      `json:"assignee_id,omitempty"`
+	Unassigned  bool       `json:"unassigned,omitempty"`
+	DueFrom     *time.Time `json:"due_from,omitempty"`
+	DueTo       *time.Time `json:"due_to,omitempty"`
+	Sort        string     `json:"sort,omitempty"`
+}
+
+// SavedView is a named task filter of a user. Shared views are listed for
+// every user of the board.
+type SavedView struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	Name      string     `gorm:"not null" json:"name"`
+	Shared    bool       `gorm:"not null;default:false" json:"shared"`
+	Filter    TaskFilter `gorm:"serializer:json" json:"filter"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
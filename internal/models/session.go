@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Session is a login of a user. The JWT handed out at login carries the
+// session ID, so revoking the session invalidates the token.
+type Session struct {
+	ID        string `gorm:"primaryKey;size:64"`
+	UserID    uint   `gorm:"index"`
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+	User      User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
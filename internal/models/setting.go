@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+const SettingRequireAdminTwoFactor = "require_admin_two_factor"
+
+// Setting is an application wide setting that admins can change at runtime.
+type Setting struct {
+	Key       string `gorm:"primaryKey;size:64"`
+	Value     string `gorm:"not null"`
+	UpdatedAt time.Time
+}
@@ -5,13 +5,23 @@ import (
 )
 
 type Task struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Title       string    `json:"title" validate:"required"`
-	Description string    `json:"description"`
-	Status      bool      `json:"status"`
-	UserID      uint      `json:"user_id" gorm:"constraint:OnDelete:CASCADE;"`
-	CategoryID  uint      `json:"category_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	User        User      `gorm:"foreignKey:UserID" json:"user"`
+	ID                uint           `gorm:"primaryKey" json:"id"`
+	Title             string         `json:"title" validate:"required"`
+	Description       string         `json:"description"`
+	Status            bool           `json:"status"`
+	UserID            uint           `json:"user_id"`
+	CategoryID        uint           `json:"category_id"`
+	AssigneeID        *uint          `json:"assignee_id"`
+	DueDate           *time.Time     `json:"due_date"`
+	DueReminderSentAt *time.Time     `json:"-"`
+	Estimate          *float64       `json:"estimate"`
+	SprintID          *uint          `gorm:"index" json:"sprint_id"`
+	RecurringTaskID   *uint          `gorm:"index" json:"recurring_task_id"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	User              User           `gorm:"foreignKey:UserID;constraint:OnDelete:RESTRICT;" json:"user"`
+	Assignee          *User          `gorm:"foreignKey:AssigneeID;constraint:OnDelete:SET NULL;" json:"assignee,omitempty"`
+	Labels            []Label        `gorm:"many2many:task_labels;constraint:OnDelete:CASCADE;" json:"labels,omitempty"`
+	Sprint            *Sprint        `gorm:"foreignKey:SprintID;constraint:OnDelete:SET NULL;" json:"sprint,omitempty"`
+	RecurringTask     *RecurringTask `gorm:"foreignKey:RecurringTaskID;constraint:OnDelete:SET NULL;" json:"-"`
 }
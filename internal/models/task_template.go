@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// TemplateChecklistItem is an item of the checklists copied to tasks
+// created from a template
+type TemplateChecklistItem struct {
+	Checklist string `json:"checklist"`
+	Text      string `json:"text"`
+}
+
+// TaskTemplate holds the defaults of a kind of task created again and
+// again. Its title pattern can use {title}, {date} and {user}. Templates are
+// shared by every board like labels.
+type TaskTemplate struct {
+	ID           uint                    `gorm:"primaryKey" json:"id"`
+	Name         string                  `gorm:"not null;uniqueIndex" json:"name"`
+	TitlePattern string                  `json:"title_pattern"`
+	Description  string                  `json:"description"`
+	CategoryID   *uint                   `json:"category_id"`
+	Labels       []Label                 `gorm:"many2many:task_template_labels;constraint:OnDelete:CASCADE;" json:"labels"`
+	Checklist    []TemplateChecklistItem `gorm:"serializer:json" json:"checklist"`
+	CreatedAt    time.Time               `json:"created_at"`
+	UpdatedAt    time.Time               `json:"updated_at"`
+	Category     *Category               `gorm:"foreignKey:CategoryID;constraint:OnDelete:SET NULL;" json:"-"`
+}
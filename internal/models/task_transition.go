@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	TransitionCreated = "created"
+	TransitionMoved   = "moved"
+	TransitionStatus  = "status"
+)
+
+// TaskTransition records a change of the category or status of a task. The
+// From fields are empty for the creation of a task.
+type TaskTransition struct {
+	ID             uint      `gorm:"primaryKey" json:"id"`
+	TaskID         uint      `gorm:"index" json:"task_id"`
+	ActorID        *uint     `json:"actor_id"`
+	Kind           string    `gorm:"not null" json:"kind"`
+	FromCategoryID *uint     `json:"from_category_id"`
+	CategoryID     uint      `json:"category_id"`
+	FromStatus     *bool     `json:"from_status"`
+	Status         bool      `json:"status"`
+	CreatedAt      time.Time `gorm:"index" json:"created_at"`
+	Task           Task      `gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE;" json:"-"`
+}
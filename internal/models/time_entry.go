@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// TimeEntry is time a user spent on a task. A running timer has no EndedAt
+// yet.
+type TimeEntry struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	TaskID    uint       `gorm:"index" json:"task_id"`
+	UserID    uint       `gorm:"index" json:"user_id"`
+	StartedAt time.Time  `gorm:"not null;index" json:"started_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	Note      string     `json:"note"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Task      Task       `gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE;" json:"-"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;" json:"-"`
+}
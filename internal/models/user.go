@@ -3,11 +3,23 @@ package models
 import "time"
 
 type User struct {
-	ID        uint   `gorm:"primaryKey" json:"id"`
-	FullName  string `json:"full_name" validate:"required"`
-	Email     string `gorm:"unique;not null" json:"email" validate:"required,email"`
-	Password  string `gorm:"not null" json:"password" validate:"required,min=6"`
-	Role      string `gorm:"not null" json:"role" validate:"required,oneof=admin member"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID                 uint       `gorm:"primaryKey" json:"id"`
+	FullName           string     `json:"full_name" validate:"required"`
+	Email              string     `gorm:"unique;not null" json:"email" validate:"required,email"`
+	Password           string     `gorm:"not null" json:"password" validate:"required,min=6"`
+	Role               string     `gorm:"not null" json:"role" validate:"required"`
+	EmailVerifiedAt    *time.Time `json:"email_verified_at"`
+	MustChangePassword bool       `gorm:"not null;default:false" json:"must_change_password"`
+	TwoFactorEnabled   bool       `gorm:"not null;default:false" json:"two_factor_enabled"`
+	TwoFactorSecret    string     `json:"-"`
+	TwoFactorLastStep  int64      `json:"-"`
+	DisabledAt         *time.Time `json:"disabled_at"`
+	// Set on the placeholder that keeps the tasks and comments of deleted users
+	Placeholder bool `gorm:"not null;default:false;index" json:"-"`
+	// Set while a deleted account can still be restored
+	DeletionScheduledAt  *time.Time `json:"deletion_scheduled_at"`
+	DeletionTaskAction   string     `json:"-"`
+	DeletionTransferToID *uint      `json:"-"`
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
 }
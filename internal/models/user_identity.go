@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external OpenID Connect
+// provider.
+type UserIdentity struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"index"`
+	Issuer    string `gorm:"not null;uniqueIndex:idx_identity_issuer_subject"`
+	Subject   string `gorm:"not null;uniqueIndex:idx_identity_issuer_subject"`
+	Email     string
+	CreatedAt time.Time
+	User      User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
+
+// OIDCLoginState remembers a login started with the provider until the user
+// comes back to the callback.
+type OIDCLoginState struct {
+	State        string `gorm:"primaryKey;size:64"`
+	Nonce        string `gorm:"not null"`
+	CodeVerifier string `gorm:"not null"`
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+const (
+	TokenPurposeVerifyEmail    = "verify_email"
+	TokenPurposeResetPassword  = "reset_password"
+	TokenPurposeRestoreAccount = "restore_account"
+)
+
+// UserToken records a single-use token sent to a user by email. The token
+// itself is a signed JWT whose ID points at this row.
+type UserToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"index"`
+	Purpose   string    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	User      User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+}
@@ -0,0 +1,204 @@
+package notifications
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// Preferences returns the saved preferences of a user, or the defaults.
+func Preferences(db *gorm.DB, userID uint) models.NotificationPreference {
+	var pref models.NotificationPreference
+	if err := db.Where("user_id = ?", userID).First(&pref).Error; err != nil {
+		return models.DefaultNotificationPreference(userID)
+	}
+	return pref
+}
+
+func wants(pref models.NotificationPreference, event Event) bool {
+	switch event {
+	case EventTaskAssigned:
+		return pref.EmailOnAssign
+	case EventTaskDueSoon:
+		return pref.EmailOnDueSoon
+	case EventTaskComment:
+		return pref.EmailOnComment
+	}
+	return false
+}
+
+// inboxMessage is the one line summary shown in the in-app inbox.
+func inboxMessage(event Event, data TemplateData) string {
+	switch event {
+	case EventTaskAssigned:
+		return fmt.Sprintf("%s assigned you the task \"%s\"", data.ActorName, data.TaskTitle)
+	case EventTaskDueSoon:
+		return fmt.Sprintf("The task \"%s\" is due on %s", data.TaskTitle, data.DueDate.Format("02 Jan 2006 15:04 MST"))
+	case EventTaskComment:
+		return fmt.Sprintf("%s commented on the task \"%s\"", data.ActorName, data.TaskTitle)
+	case EventTaskMoved:
+		return fmt.Sprintf("%s moved the task \"%s\" to %s", data.ActorName, data.TaskTitle, data.Category)
+	}
+	return data.TaskTitle
+}
+
+// notify stores an in-app notification for the recipient and queues an email
+// when the recipient wants one for this event.
+func notify(db *gorm.DB, event Event, recipient models.User, actor *models.User, data TemplateData) {
+	data.RecipientName = recipient.FullName
+	if actor != nil {
+		data.ActorName = actor.FullName
+	}
+
+	notification := models.Notification{
+		UserID:  recipient.ID,
+		TaskID:  &data.TaskID,
+		Type:    string(event),
+		Message: inboxMessage(event, data),
+	}
+	if actor != nil {
+		notification.ActorID = &actor.ID
+	}
+	if err := db.Create(&notification).Error; err != nil {
+		log.Printf("Failed to store %s notification: %v", event, err)
+	}
+
+	if _, ok := templates[event]; !ok || !wants(Preferences(db, recipient.ID), event) {
+		return
+	}
+
+	subject, body, err := Render(event, data)
+	if err != nil {
+		log.Printf("Failed to render %s notification: %v", event, err)
+		return
+	}
+
+	Enqueue(Message{To: recipient.Email, Subject: subject, Body: body})
+}
+
+// AccountEmail sends an account related email, such as a verification or a
+// password reset link. It ignores notification preferences and the inbox.
+func AccountEmail(event Event, recipient models.User, link string) {
+	subject, body, err := Render(event, TemplateData{RecipientName: recipient.FullName, Link: link})
+	if err != nil {
+		log.Printf("Failed to render %s email: %v", event, err)
+		return
+	}
+
+	Enqueue(Message{To: recipient.Email, Subject: subject, Body: body})
+}
+
+// AccountDeletionScheduled tells a user when their account will be deleted,
+// how to restore it until then and how to get a copy of their data.
+func AccountDeletionScheduled(recipient models.User, restoreLink string, deleteAt time.Time, dataExport bool) {
+	subject, body, err := Render(EventAccountDeletion, TemplateData{RecipientName: recipient.FullName, Link: restoreLink, Date: &deleteAt, DataExport: dataExport})
+	if err != nil {
+		log.Printf("Failed to render %s email: %v", EventAccountDeletion, err)
+		return
+	}
+
+	Enqueue(Message{To: recipient.Email, Subject: subject, Body: body})
+}
+
+// TaskAssigned tells the assignee of a task that it was given to them.
+func TaskAssigned(db *gorm.DB, task models.Task, actor models.User) {
+	if task.AssigneeID == nil || *task.AssigneeID == actor.ID {
+		return
+	}
+
+	var assignee models.User
+	if err := db.First(&assignee, *task.AssigneeID).Error; err != nil {
+		return
+	}
+
+	notify(db, EventTaskAssigned, assignee, &actor, TemplateData{
+		TaskID:    task.ID,
+		TaskTitle: task.Title,
+		DueDate:   task.DueDate,
+	})
+}
+
+// TaskCommented tells everyone involved in a task about a new comment,
+// except the author of the comment.
+func TaskCommented(db *gorm.DB, task models.Task, comment models.Comment, actor models.User) {
+	for _, recipient := range involvedUsers(db, task) {
+		if recipient.ID == actor.ID {
+			continue
+		}
+		notify(db, EventTaskComment, recipient, &actor, TemplateData{
+			TaskID:    task.ID,
+			TaskTitle: task.Title,
+			Comment:   comment.Body,
+		})
+	}
+}
+
+// TaskMoved tells everyone involved in a task that it moved to another
+// category. It only shows up in the in-app inbox.
+func TaskMoved(db *gorm.DB, task models.Task, category models.Category, actor models.User) {
+	for _, recipient := range involvedUsers(db, task) {
+		if recipient.ID == actor.ID {
+			continue
+		}
+		notify(db, EventTaskMoved, recipient, &actor, TemplateData{
+			TaskID:    task.ID,
+			TaskTitle: task.Title,
+			Category:  category.Type,
+		})
+	}
+}
+
+// involvedUsers returns the owner, the assignee and the commenters of a task.
+func involvedUsers(db *gorm.DB, task models.Task) []models.User {
+	ids := []uint{task.UserID}
+	if task.AssigneeID != nil {
+		ids = append(ids, *task.AssigneeID)
+	}
+
+	var commenterIDs []uint
+	db.Model(&models.Comment{}).Where("task_id = ?", task.ID).Distinct().Pluck("user_id", &commenterIDs)
+	ids = append(ids, commenterIDs...)
+
+	var users []models.User
+	db.Where("id IN ?", ids).Find(&users)
+	return users
+}
+
+// SendDueSoonReminders notifies the people involved in open tasks that are
+// due within the given window. Each task is reminded only once.
+func SendDueSoonReminders(db *gorm.DB, window time.Duration) {
+	now := time.Now()
+
+	var tasks []models.Task
+	if err := db.Where("status = ? AND due_date IS NOT NULL AND due_date > ? AND due_date <= ? AND due_reminder_sent_at IS NULL",
+		false, now, now.Add(window)).Find(&tasks).Error; err != nil {
+		log.Printf("Failed to look up tasks due soon: %v", err)
+		return
+	}
+
+	for _, task := range tasks {
+		for _, recipient := range involvedUsers(db, task) {
+			notify(db, EventTaskDueSoon, recipient, nil, TemplateData{
+				TaskID:    task.ID,
+				TaskTitle: task.Title,
+				DueDate:   task.DueDate,
+			})
+		}
+		db.Model(&task).UpdateColumn("due_reminder_sent_at", now)
+	}
+}
+
+// StartDueSoonScheduler checks for tasks due soon on every interval.
+func StartDueSoonScheduler(db *gorm.DB, interval, window time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			SendDueSoonReminders(db, window)
+			<-ticker.C
+		}
+	}()
+}
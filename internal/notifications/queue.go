@@ -0,0 +1,70 @@
+package notifications
+
+import (
+	"log"
+	"sync"
+)
+
+// Queue sends messages in the background so request handlers never wait on
+// the mail server.
+type Queue struct {
+	mailer Mailer
+	jobs   chan Message
+	wg     sync.WaitGroup
+}
+
+func NewQueue(mailer Mailer, size, workers int) *Queue {
+	q := &Queue{
+		mailer: mailer,
+		jobs:   make(chan Message, size),
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for msg := range q.jobs {
+		if err := q.mailer.Send(msg); err != nil {
+			log.Printf("Failed to send mail to %s: %v", msg.To, err)
+		}
+	}
+}
+
+// Enqueue adds a message without blocking. It reports false when the queue
+// is full and the message was dropped.
+func (q *Queue) Enqueue(msg Message) bool {
+	select {
+	case q.jobs <- msg:
+		return true
+	default:
+		log.Printf("Mail queue is full, dropping message to %s", msg.To)
+		return false
+	}
+}
+
+// Close stops accepting messages and waits for the pending ones to be sent.
+func (q *Queue) Close() {
+	close(q.jobs)
+	q.wg.Wait()
+}
+
+var defaultQueue *Queue
+
+// Start sets up the queue used by the package level notify functions.
+func Start(mailer Mailer) {
+	defaultQueue = NewQueue(mailer, 256, 2)
+}
+
+// Enqueue puts a message on the default queue. Messages are dropped when
+// Start has not been called.
+func Enqueue(msg Message) {
+	if defaultQueue == nil {
+		log.Printf("Mail queue is not started, dropping message to %s", msg.To)
+		return
+	}
+	defaultQueue.Enqueue(msg)
+}
@@ -0,0 +1,140 @@
+package notifications
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+)
+
+// Event identifies the kind of notification being sent.
+type Event string
+
+const (
+	EventTaskAssigned Event = "task_assigned"
+	EventTaskDueSoon  Event = "task_due_soon"
+	EventTaskComment  Event = "task_comment"
+	EventTaskMoved    Event = "task_moved"
+
+	EventVerifyEmail     Event = "verify_email"
+	EventPasswordReset   Event = "password_reset"
+	EventAccountDeletion Event = "account_deletion"
+	EventDataExport      Event = "data_export"
+)
+
+// TemplateData is the data available to every message template.
+type TemplateData struct {
+	RecipientName string
+	ActorName     string
+	TaskID        uint
+	TaskTitle     string
+	DueDate       *time.Time
+	Comment       string
+	Category      string
+	Link          string
+	Date          *time.Time
+	// DataExport is set when a copy of the user's data is being prepared
+	DataExport bool
+}
+
+type messageTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+var templates = map[Event]messageTemplate{
+	EventTaskAssigned: newTemplate(
+		`Task assigned: {{.TaskTitle}}`,
+		`Hi {{.RecipientName}},
+
+{{.ActorName}} assigned you the task "{{.TaskTitle}}" (#{{.TaskID}}).
+{{if .DueDate}}It is due on {{.DueDate.Format "02 Jan 2006 15:04 MST"}}.
+{{end}}`),
+	EventTaskDueSoon: newTemplate(
+		`Task due soon: {{.TaskTitle}}`,
+		`Hi {{.RecipientName}},
+
+The task "{{.TaskTitle}}" (#{{.TaskID}}) is due on {{.DueDate.Format "02 Jan 2006 15:04 MST"}}.
+`),
+	EventTaskComment: newTemplate(
+		`New comment on: {{.TaskTitle}}`,
+		`Hi {{.RecipientName}},
+
+{{.ActorName}} commented on the task "{{.TaskTitle}}" (#{{.TaskID}}):
+
+{{.Comment}}
+`),
+	EventVerifyEmail: newTemplate(
+		`Verify your email address`,
+		`Hi {{.RecipientName}},
+
+Please confirm your email address by opening the link below:
+
+{{.Link}}
+
+If you did not create an account you can ignore this email.
+`),
+	EventPasswordReset: newTemplate(
+		`Reset your password`,
+		`Hi {{.RecipientName}},
+
+Someone asked to reset the password of your account. Open the link below to
+choose a new password:
+
+{{.Link}}
+
+The link can be used once and expires soon. If you did not ask for this you
+can ignore this email.
+`),
+	EventDataExport: newTemplate(
+		`Your data export is ready`,
+		`Hi {{.RecipientName}},
+
+The export of your data is ready. Download it with the link below:
+
+{{.Link}}
+
+The link expires in 24 hours.
+`),
+	EventAccountDeletion: newTemplate(
+		`Your account will be deleted`,
+		`Hi {{.RecipientName}},
+
+Your account has been deactivated and will be deleted for good on
+{{.Date.Format "02 Jan 2006 15:04 MST"}}. Until then you can restore it by
+opening the link below:
+
+{{.Link}}
+{{if .DataExport}}
+A copy of your data is being prepared. We will email you a link to
+download it once it is ready. The link expires in 24 hours. If it runs out
+before the account is deleted, request a new export with POST /users/export
+from the session that deleted the account.
+{{else}}
+You can still download a copy of your data before the account is deleted,
+request it with POST /users/export from the session that deleted the
+account. The download link expires in 24 hours.
+{{end}}
+If you did not ask for this, restore your account and change your password.
+`),
+}
+
+func newTemplate(subject, body string) messageTemplate {
+	return messageTemplate{
+		subject: template.Must(template.New("subject").Parse(subject)),
+		body:    template.Must(template.New("body").Parse(body)),
+	}
+}
+
+// Render builds the subject and body for an event.
+func Render(event Event, data TemplateData) (string, string, error) {
+	tmpl := templates[event]
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return "", "", err
+	}
+	return subject.String(), body.String(), nil
+}
@@ -0,0 +1,227 @@
+// Package policy decides what a user may do. Permissions are named after the
+// resource and action, for example "task.update.any". Roles are sets of
+// permissions: the built-in admin and member roles, plus custom roles stored
+// in the database.
+package policy
+
+import (
+	"sort"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+type Permission string
+
+const (
+	TaskCreate    Permission = "task.create"
+	TaskReadOwn   Permission = "task.read.own"
+	TaskReadAny   Permission = "task.read.any"
+	TaskUpdateOwn Permission = "task.update.own"
+	TaskUpdateAny Permission = "task.update.any"
+	TaskDeleteOwn Permission = "task.delete.own"
+	TaskDeleteAny Permission = "task.delete.any"
+
+	CommentCreateOwn Permission = "comment.create.own"
+	CommentCreateAny Permission = "comment.create.any"
+
+	CategoryManage Permission = "category.manage"
+	SprintManage   Permission = "sprint.manage"
+	UserManage     Permission = "user.manage"
+	RoleManage     Permission = "role.manage"
+	SettingsManage Permission = "settings.manage"
+	AuditRead      Permission = "audit.read"
+)
+
+// Actions checked against a resource. Can picks the .own or .any
+// permission depending on the actor's relation to the resource.
+const (
+	TaskRead      = "task.read"
+	TaskUpdate    = "task.update"
+	TaskDelete    = "task.delete"
+	CommentCreate = "comment.create"
+)
+
+// All lists every permission, in the order they are shown to admins.
+var All = []Permission{
+	TaskCreate, TaskReadOwn, TaskReadAny, TaskUpdateOwn, TaskUpdateAny, TaskDeleteOwn, TaskDeleteAny,
+	CommentCreateOwn, CommentCreateAny,
+	CategoryManage, SprintManage, UserManage, RoleManage, SettingsManage, AuditRead,
+}
+
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
+// BuiltinRoles are the roles that always exist.
+var BuiltinRoles = map[string][]Permission{
+	RoleAdmin: All,
+	RoleMember: {
+		TaskCreate, TaskReadOwn, TaskUpdateOwn, TaskDeleteOwn,
+		CommentCreateOwn,
+	},
+}
+
+// involvedActions treat the assignee of a task like its owner. Only the owner
+// may change or delete a task.
+var involvedActions = map[string]bool{
+	TaskRead:      true,
+	CommentCreate: true,
+}
+
+// IsValid reports whether p is a known permission.
+func IsValid(p Permission) bool {
+	return contains(All, p)
+}
+
+// RoleExists reports whether a role with the name exists.
+func RoleExists(db *gorm.DB, name string) bool {
+	if _, ok := BuiltinRoles[name]; ok {
+		return true
+	}
+	var count int64
+	db.Model(&models.Role{}).Where("name = ?", name).Count(&count)
+	return count > 0
+}
+
+// Permissions returns the permissions granted by a role, sorted.
+func Permissions(db *gorm.DB, role string) []Permission {
+	if permissions, ok := BuiltinRoles[role]; ok {
+		return permissions
+	}
+
+	var names []string
+	db.Model(&models.RolePermission{}).
+		Joins("JOIN roles ON roles.id = role_permissions.role_id").
+		Where("roles.name = ?", role).
+		Pluck("role_permissions.permission", &names)
+	sort.Strings(names)
+
+	permissions := make([]Permission, len(names))
+	for i, name := range names {
+		permissions[i] = Permission(name)
+	}
+	return permissions
+}
+
+// Grants reports whether the role has every one of the permissions.
+func Grants(db *gorm.DB, role string, permissions []Permission) bool {
+	granted := Permissions(db, role)
+	for _, p := range permissions {
+		if !contains(granted, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsFull reports whether the permissions include every permission there is.
+func IsFull(permissions []Permission) bool {
+	for _, p := range All {
+		if !contains(permissions, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPrivileged reports whether the role grants more than a member has.
+func IsPrivileged(db *gorm.DB, role string) bool {
+	for _, p := range Permissions(db, role) {
+		if !contains(BuiltinRoles[RoleMember], p) {
+			return true
+		}
+	}
+	return false
+}
+
+// FullAccessRoles returns the names of the roles that have every
+// permission. Users with one of them are the admins.
+func FullAccessRoles(db *gorm.DB) []string {
+	roles := []string{RoleAdmin}
+
+	var names []string
+	db.Model(&models.Role{}).Order("name").Pluck("name", &names)
+	for _, name := range names {
+		if Grants(db, name, All) {
+			roles = append(roles, name)
+		}
+	}
+	return roles
+}
+
+// Actor is the user making a request.
+type Actor struct {
+	models.User
+	// Restricted limits the actor to what a member may do, for personal
+	// access tokens without the admin scope
+	Restricted bool
+}
+
+// Permissions returns what the actor may do.
+func (a Actor) Permissions(db *gorm.DB) []Permission {
+	permissions := Permissions(db, a.Role)
+	if !a.Restricted {
+		return permissions
+	}
+
+	var allowed []Permission
+	for _, p := range permissions {
+		if contains(BuiltinRoles[RoleMember], p) {
+			allowed = append(allowed, p)
+		}
+	}
+	return allowed
+}
+
+func contains(permissions []Permission, permission Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether the actor has the permission.
+func Has(db *gorm.DB, actor Actor, permission Permission) bool {
+	return contains(actor.Permissions(db), permission)
+}
+
+// HasAll reports whether the actor has every one of the permissions. Actors
+// can only hand out permissions they have themselves.
+func HasAll(db *gorm.DB, actor Actor, permissions []Permission) bool {
+	held := actor.Permissions(db)
+	for _, p := range permissions {
+		if !contains(held, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Covers reports whether the actor has every permission of the role, so they
+// may give it to a user or act on users that have it.
+func Covers(db *gorm.DB, actor Actor, role string) bool {
+	return HasAll(db, actor, Permissions(db, role))
+}
+
+// Can reports whether the actor may perform the action on a task. The .any
+// permission allows it on every task, the .own permission only on tasks the
+// actor owns (or is assigned to, for reading and commenting).
+func Can(db *gorm.DB, actor Actor, action string, task models.Task) bool {
+	permissions := actor.Permissions(db)
+
+	if contains(permissions, Permission(action+".any")) {
+		return true
+	}
+	if !contains(permissions, Permission(action+".own")) {
+		return false
+	}
+
+	if task.UserID == actor.ID {
+		return true
+	}
+	return involvedActions[action] && task.AssigneeID != nil && *task.AssigneeID == actor.ID
+}
@@ -0,0 +1,257 @@
+// Package recurrence creates the tasks of recurring series. Series follow a
+// subset of RFC 5545 recurrence rules.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+)
+
+// maxPeriods bounds the search for an occurrence, so a rule that never
+// matches cannot loop forever
+const maxPeriods = 1000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Rule is a recurrence rule: FREQ=DAILY, FREQ=WEEKLY with BYDAY or
+// FREQ=MONTHLY with BYMONTHDAY, each with an optional INTERVAL, COUNT and
+// UNTIL. Occurrences keep the time of day of the start of the series.
+type Rule struct {
+	Freq       string
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	// Count is the number of tasks created by the series, 0 for no limit
+	Count int
+	Until *time.Time
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=MO,WE;INTERVAL=2". A
+// leading "RRULE:" is allowed.
+func Parse(value string) (*Rule, error) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("rule is required")
+	}
+
+	rule := &Rule{Interval: 1}
+	for _, part := range strings.Split(value, ";") {
+		name, arg, ok := strings.Cut(part, "=")
+		if !ok || arg == "" {
+			return nil, fmt.Errorf("invalid rule part %q", part)
+		}
+
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Freq = strings.ToUpper(arg)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return nil, errors.New("FREQ must be DAILY, WEEKLY or MONTHLY")
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 || n > 366 {
+				return nil, errors.New("INTERVAL must be between 1 and 366")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				return nil, errors.New("COUNT must be a positive number")
+			}
+			rule.Count = n
+		case "UNTIL":
+			until, err := parseUntil(arg)
+			if err != nil {
+				return nil, errors.New("UNTIL must look like 20060102 or 20060102T150405Z")
+			}
+			rule.Until = &until
+		case "BYDAY":
+			for _, day := range strings.Split(strings.ToUpper(arg), ",") {
+				weekday, ok := weekdays[day]
+				if !ok {
+					return nil, fmt.Errorf("invalid BYDAY %q, use MO, TU, WE, TH, FR, SA or SU", day)
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(arg, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid BYMONTHDAY %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		default:
+			return nil, fmt.Errorf("%s is not supported", strings.ToUpper(name))
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, errors.New("FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, errors.New("COUNT and UNTIL cannot both be set")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return nil, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return nil, errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+
+	return rule, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("20060102", value)
+	if err != nil {
+		return t, err
+	}
+	// A date includes the whole day
+	return t.Add(24*time.Hour - time.Nanosecond), nil
+}
+
+// String writes the rule back in its canonical form
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		var days []string
+		for _, weekday := range r.ByDay {
+			for name, day := range weekdays {
+				if day == weekday {
+					days = append(days, name)
+				}
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		var days []string
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence of a series starting at start that
+// comes after after. It returns false once the series is over by UNTIL.
+func (r *Rule) Next(start, after time.Time) (time.Time, bool) {
+	first := r.firstPeriod(start, after)
+	for period := first; period < first+maxPeriods; period++ {
+		for _, occurrence := range r.occurrences(start, period) {
+			if occurrence.Before(start) || !occurrence.After(after) {
+				continue
+			}
+			if r.Until != nil && occurrence.After(*r.Until) {
+				return time.Time{}, false
+			}
+			return occurrence, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// firstPeriod skips the periods that end before after
+func (r *Rule) firstPeriod(start, after time.Time) int {
+	if !after.After(start) {
+		return 0
+	}
+	var periods int
+	switch r.Freq {
+	case Daily:
+		periods = int(after.Sub(start).Hours()/24) / r.Interval
+	case Weekly:
+		periods = int(after.Sub(start).Hours()/(24*7)) / r.Interval
+	case Monthly:
+		months := (after.Year()-start.Year())*12 + int(after.Month()-start.Month())
+		periods = months / r.Interval
+	}
+	// Leave a period of margin for daylight saving time and short months
+	if periods > 0 {
+		periods--
+	}
+	return periods
+}
+
+// occurrences lists the occurrences in a period of the rule, in order
+func (r *Rule) occurrences(start time.Time, period int) []time.Time {
+	at := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, start.Hour(), start.Minute(), start.Second(), 0, start.Location())
+	}
+
+	switch r.Freq {
+	case Daily:
+		day := start.AddDate(0, 0, period*r.Interval)
+		return []time.Time{at(day.Year(), day.Month(), day.Day())}
+
+	case Weekly:
+		// Weeks start on Monday
+		monday := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+7*period*r.Interval)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		var result []time.Time
+		for _, weekday := range days {
+			day := monday.AddDate(0, 0, (int(weekday)+6)%7)
+			result = append(result, at(day.Year(), day.Month(), day.Day()))
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+		return result
+
+	case Monthly:
+		month := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location()).AddDate(0, period*r.Interval, 0)
+		daysInMonth := time.Date(month.Year(), month.Month()+1, 0, 0, 0, 0, 0, start.Location()).Day()
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		var result []time.Time
+		for _, day := range days {
+			if day < 0 {
+				day = daysInMonth + day + 1
+			}
+			// Months without the day are skipped
+			if day < 1 || day > daysInMonth {
+				continue
+			}
+			result = append(result, at(month.Year(), month.Month(), day))
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+		return result
+	}
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+)
+
+func date(year int, month time.Month, day, hour int, loc *time.Location) time.Time {
+	return time.Date(year, month, day, hour, 0, 0, 0, loc)
+}
+
+func mustParse(t *testing.T, value string) *Rule {
+	t.Helper()
+	rule, err := Parse(value)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", value, err)
+	}
+	return rule
+}
+
+func TestNext(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data is not available: %v", err)
+	}
+	utc := time.UTC
+	// 1 January 2025 is a Wednesday
+	wednesday := date(2025, time.January, 1, 9, utc)
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		after time.Time
+		want  time.Time
+		ok    bool
+	}{
+		{"weekly every other week from mid-week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", wednesday, wednesday.Add(-time.Nanosecond), date(2025, time.January, 3, 9, utc), true},
+		{"weekly skips the week in between", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", wednesday, date(2025, time.January, 3, 9, utc), date(2025, time.January, 13, 9, utc), true},
+		{"weekly later in the same week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", wednesday, date(2025, time.January, 13, 9, utc), date(2025, time.January, 17, 9, utc), true},
+		{"weekly months later", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", wednesday, date(2025, time.June, 1, 12, utc), date(2025, time.June, 2, 9, utc), true},
+		{"day 31 skips February", "FREQ=MONTHLY;BYMONTHDAY=31", date(2025, time.January, 31, 9, utc), date(2025, time.January, 31, 9, utc), date(2025, time.March, 31, 9, utc), true},
+		{"day 31 skips April", "FREQ=MONTHLY;BYMONTHDAY=31", date(2025, time.January, 31, 9, utc), date(2025, time.March, 31, 9, utc), date(2025, time.May, 31, 9, utc), true},
+		{"last day of February", "FREQ=MONTHLY;BYMONTHDAY=-1", date(2025, time.January, 31, 9, utc), date(2025, time.January, 31, 9, utc), date(2025, time.February, 28, 9, utc), true},
+		{"last day of February in a leap year", "FREQ=MONTHLY;BYMONTHDAY=-1", date(2024, time.January, 31, 9, utc), date(2024, time.January, 31, 9, utc), date(2024, time.February, 29, 9, utc), true},
+		{"until a date includes that day", "FREQ=DAILY;UNTIL=20250103", wednesday, date(2025, time.January, 2, 9, utc), date(2025, time.January, 3, 9, utc), true},
+		{"until a date is over the day after", "FREQ=DAILY;UNTIL=20250103", wednesday, date(2025, time.January, 3, 9, utc), time.Time{}, false},
+		{"daily into summer time", "FREQ=DAILY", date(2025, time.March, 29, 9, berlin), date(2025, time.March, 29, 9, berlin), date(2025, time.March, 30, 9, berlin), true},
+		{"daily weeks after summer time", "FREQ=DAILY", date(2025, time.March, 1, 9, berlin), date(2025, time.April, 10, 8, berlin), date(2025, time.April, 10, 9, berlin), true},
+		{"weekly out of summer time", "FREQ=WEEKLY", date(2025, time.October, 20, 9, berlin), date(2025, time.October, 20, 9, berlin), date(2025, time.October, 27, 9, berlin), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mustParse(t, tt.rule).Next(tt.start, tt.after)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("Next(%v, %v) = %v, %v, want %v, %v", tt.start, tt.after, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestFirstPeriod(t *testing.T) {
+	utc := time.UTC
+	wednesday := date(2025, time.January, 1, 9, utc)
+
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		after time.Time
+		want  int
+	}{
+		{"after before the start", "FREQ=DAILY", wednesday, wednesday.AddDate(0, 0, -3), 0},
+		{"every third day", "FREQ=DAILY;INTERVAL=3", wednesday, date(2025, time.January, 11, 9, utc), 2},
+		{"every other week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", wednesday, date(2025, time.June, 1, 12, utc), 9},
+		{"monthly from the end of January", "FREQ=MONTHLY;BYMONTHDAY=31", date(2025, time.January, 31, 9, utc), date(2025, time.March, 1, 9, utc), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := mustParse(t, tt.rule)
+			got := rule.firstPeriod(tt.start, tt.after)
+			if got != tt.want {
+				t.Errorf("firstPeriod(%v, %v) = %d, want %d", tt.start, tt.after, got, tt.want)
+			}
+			// The skipped periods must not hold an occurrence after after
+			for period := 0; period < got; period++ {
+				for _, occurrence := range rule.occurrences(tt.start, period) {
+					if occurrence.After(tt.after) {
+						t.Errorf("skipped period %d has %v, after %v", period, occurrence, tt.after)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	utc := time.UTC
+	wednesday := date(2025, time.January, 1, 9, utc)
+
+	tests := []struct {
+		name   string
+		rule   string
+		start  time.Time
+		period int
+		want   []time.Time
+	}{
+		{"weekly from Monday, in order", "FREQ=WEEKLY;BYDAY=FR,MO", wednesday, 0, []time.Time{date(2024, time.December, 30, 9, utc), date(2025, time.January, 3, 9, utc)}},
+		{"weekly defaults to the start day", "FREQ=WEEKLY;INTERVAL=2", wednesday, 1, []time.Time{date(2025, time.January, 15, 9, utc)}},
+		{"daily interval", "FREQ=DAILY;INTERVAL=3", wednesday, 2, []time.Time{date(2025, time.January, 7, 9, utc)}},
+		{"February has no day 31", "FREQ=MONTHLY;BYMONTHDAY=31", date(2025, time.January, 31, 9, utc), 1, nil},
+		{"last day and first day of February", "FREQ=MONTHLY;BYMONTHDAY=-1,1", date(2025, time.January, 31, 9, utc), 1, []time.Time{date(2025, time.February, 1, 9, utc), date(2025, time.February, 28, 9, utc)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mustParse(t, tt.rule).occurrences(tt.start, tt.period)
+			if len(got) != len(tt.want) {
+				t.Fatalf("occurrences(%v, %d) = %v, want %v", tt.start, tt.period, got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrences(%v, %d) = %v, want %v", tt.start, tt.period, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduleStopsAfterCount(t *testing.T) {
+	start := date(2025, time.January, 1, 9, time.UTC)
+	now := date(2025, time.January, 2, 12, time.UTC)
+
+	for occurrences, scheduled := range map[int]bool{0: true, 1: true, 2: false} {
+		series := models.RecurringTask{Rule: "FREQ=DAILY;COUNT=2", StartAt: start, Occurrences: occurrences}
+		if err := Schedule(nil, &series, now); err != nil {
+			t.Fatalf("Schedule: %v", err)
+		}
+		if (series.NextAt != nil) != scheduled {
+			t.Errorf("after %d of 2 tasks the next task is at %v, want scheduled %v", occurrences, series.NextAt, scheduled)
+		}
+	}
+}
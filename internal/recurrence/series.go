@@ -0,0 +1,153 @@
+package recurrence
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/analytics"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/notifications"
+	"gorm.io/gorm"
+)
+
+// errTaken means another run already created the task
+var errTaken = errors.New("the next task of the series was already created")
+
+// Schedule sets when the next task of a series is created: at its first
+// occurrence from now on. A series created on completion waits while its last
+// task is open, and a series that is over has no next task.
+func Schedule(db *gorm.DB, series *models.RecurringTask, now time.Time) error {
+	rule, err := Parse(series.Rule)
+	if err != nil {
+		return err
+	}
+	series.NextAt = nil
+
+	if rule.Count > 0 && series.Occurrences >= rule.Count {
+		return nil
+	}
+	if series.OnCompletion && series.LastTaskID != nil {
+		var last models.Task
+		if err := db.First(&last, *series.LastTaskID).Error; err == nil && !last.Status {
+			return nil
+		}
+	}
+
+	if next, ok := rule.Next(series.StartAt, now.Add(-time.Nanosecond)); ok {
+		series.NextAt = &next
+	}
+	return nil
+}
+
+// Materialize creates the task of a series due at dueAt and schedules the
+// next one
+func Materialize(db *gorm.DB, series *models.RecurringTask, dueAt time.Time) (*models.Task, error) {
+	rule, err := Parse(series.Rule)
+	if err != nil {
+		return nil, err
+	}
+
+	task := models.Task{
+		Title:           series.Title,
+		Description:     series.Description,
+		UserID:          series.UserID,
+		CategoryID:      series.CategoryID,
+		AssigneeID:      series.AssigneeID,
+		DueDate:         &dueAt,
+		RecurringTaskID: &series.ID,
+	}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// Claim the occurrence so it is only created once
+		claim := tx.Model(&models.RecurringTask{}).Where("id = ? AND occurrences = ?", series.ID, series.Occurrences).
+			Update("occurrences", series.Occurrences+1)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return errTaken
+		}
+
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		if err := analytics.RecordCreated(tx, task, series.UserID); err != nil {
+			return err
+		}
+
+		series.Occurrences++
+		series.LastTaskID = &task.ID
+		series.NextAt = nil
+		if !series.OnCompletion && (rule.Count == 0 || series.Occurrences < rule.Count) {
+			// Occurrences missed while the server was down are skipped
+			after := dueAt
+			if now := time.Now(); now.After(after) {
+				after = now
+			}
+			if next, ok := rule.Next(series.StartAt, after); ok {
+				series.NextAt = &next
+			}
+		}
+		return tx.Model(series).Select("last_task_id", "next_at").Updates(series).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var owner models.User
+	if db.First(&owner, series.UserID).Error == nil {
+		notifications.TaskAssigned(db, task, owner)
+	}
+
+	return &task, nil
+}
+
+// TaskCompleted creates the next task of a series created on completion,
+// due at the next occurrence, once its last task is done
+func TaskCompleted(db *gorm.DB, task models.Task) {
+	if task.RecurringTaskID == nil {
+		return
+	}
+
+	var series models.RecurringTask
+	if err := db.First(&series, *task.RecurringTaskID).Error; err != nil {
+		return
+	}
+	if !series.OnCompletion || series.Paused || series.LastTaskID == nil || *series.LastTaskID != task.ID {
+		return
+	}
+
+	if err := Schedule(db, &series, time.Now()); err != nil || series.NextAt == nil {
+		return
+	}
+	if _, err := Materialize(db, &series, *series.NextAt); err != nil && err != errTaken {
+		log.Printf("Failed to create the next task of recurring task %d: %v", series.ID, err)
+	}
+}
+
+// RunDue creates the tasks of the series whose next occurrence has come
+func RunDue(db *gorm.DB) {
+	var due []models.RecurringTask
+	if err := db.Where("paused = ? AND next_at IS NOT NULL AND next_at <= ?", false, time.Now()).Find(&due).Error; err != nil {
+		log.Printf("Failed to look up due recurring tasks: %v", err)
+		return
+	}
+
+	for _, series := range due {
+		if _, err := Materialize(db, &series, *series.NextAt); err != nil && err != errTaken {
+			log.Printf("Failed to create the next task of recurring task %d: %v", series.ID, err)
+		}
+	}
+}
+
+// StartScheduler creates the due tasks of recurring series on every interval.
+func StartScheduler(db *gorm.DB, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			RunDue(db)
+			<-ticker.C
+		}
+	}()
+}
@@ -13,11 +13,56 @@ func RegisterRoutes(router *mux.Router, db *gorm.DB) {
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintln(w, "API Project 3 Kelompok 2")
 	})
+	router.HandleFunc("/setup", controllers.SetupAdmin(db)).Methods("POST")
+	router.HandleFunc("/.well-known/jwks.json", controllers.GetJWKS()).Methods("GET")
+
 	// User routes
 	router.HandleFunc("/users/register", controllers.RegisterUser(db)).Methods("POST")
 	router.HandleFunc("/users/login", controllers.LoginUser(db)).Methods("POST")
+	router.HandleFunc("/users/login/2fa", controllers.LoginTwoFactor(db)).Methods("POST")
+	router.HandleFunc("/users/oidc/login", controllers.OIDCLogin(db)).Methods("GET")
+	router.HandleFunc("/users/oidc/callback", controllers.OIDCCallback(db)).Methods("GET")
+	router.HandleFunc("/users/verify-email", controllers.VerifyEmail(db)).Methods("POST")
+	router.HandleFunc("/users/resend-verification", controllers.ResendVerificationEmail(db)).Methods("POST")
+	router.HandleFunc("/users/forgot-password", controllers.ForgotPassword(db)).Methods("POST")
+	router.HandleFunc("/users/reset-password", controllers.ResetPassword(db)).Methods("POST")
 	router.HandleFunc("/users/update-account", controllers.UpdateUserAccount(db)).Methods("PUT")
+	router.HandleFunc("/users/change-password", controllers.ChangePassword(db)).Methods("PUT")
+	router.HandleFunc("/users/restore-account", controllers.RestoreAccount(db)).Methods("POST")
+	router.HandleFunc("/users/export", controllers.RequestDataExport(db)).Methods("POST")
+	router.HandleFunc("/users/exports/{exportId}", controllers.GetDataExport(db)).Methods("GET")
+	router.HandleFunc("/users/exports/{exportId}/download", controllers.DownloadDataExport(db)).Methods("GET")
 	router.HandleFunc("/users/delete-account", controllers.DeleteUserAccount(db)).Methods("DELETE")
+	router.HandleFunc("/users/notification-preferences", controllers.GetNotificationPreferences(db)).Methods("GET")
+	router.HandleFunc("/users/notification-preferences", controllers.UpdateNotificationPreferences(db)).Methods("PUT")
+	router.HandleFunc("/users/tokens", controllers.CreatePersonalToken(db)).Methods("POST")
+	router.HandleFunc("/users/tokens", controllers.GetPersonalTokens(db)).Methods("GET")
+	router.HandleFunc("/users/tokens/{tokenId}", controllers.RevokePersonalToken(db)).Methods("DELETE")
+	router.HandleFunc("/users/calendar-feed", controllers.GetCalendarFeed(db)).Methods("GET")
+	router.HandleFunc("/users/calendar-feed", controllers.CreateCalendarFeed(db)).Methods("POST")
+	router.HandleFunc("/users/calendar-feed", controllers.DeleteCalendarFeed(db)).Methods("DELETE")
+	router.HandleFunc("/users/2fa/setup", controllers.SetupTwoFactor(db)).Methods("POST")
+	router.HandleFunc("/users/2fa/enable", controllers.EnableTwoFactor(db)).Methods("POST")
+	router.HandleFunc("/users/2fa/disable", controllers.DisableTwoFactor(db)).Methods("POST")
+	router.HandleFunc("/users/2fa/recovery-codes", controllers.RegenerateRecoveryCodes(db)).Methods("POST")
+
+	// Admin routes
+	router.HandleFunc("/admin/security-settings", controllers.GetSecuritySettings(db)).Methods("GET")
+	router.HandleFunc("/admin/security-settings", controllers.UpdateSecuritySettings(db)).Methods("PUT")
+	router.HandleFunc("/admin/login-attempts", controllers.GetLoginAttempts(db)).Methods("GET")
+	router.HandleFunc("/admin/users", controllers.GetUsers(db)).Methods("GET")
+	router.HandleFunc("/admin/users/{userId}", controllers.GetUser(db)).Methods("GET")
+	router.HandleFunc("/admin/users/{userId}/tasks", controllers.GetUserTasks(db)).Methods("GET")
+	router.HandleFunc("/admin/users/{userId}/disable", controllers.DisableUser(db)).Methods("POST")
+	router.HandleFunc("/admin/users/{userId}/enable", controllers.EnableUser(db)).Methods("POST")
+	router.HandleFunc("/admin/users/{userId}/force-password-reset", controllers.ForcePasswordReset(db)).Methods("POST")
+	router.HandleFunc("/admin/users/{userId}/unlock", controllers.UnlockUser(db)).Methods("POST")
+	router.HandleFunc("/admin/users/{userId}/role", controllers.UpdateUserRole(db)).Methods("PUT")
+	router.HandleFunc("/admin/permissions", controllers.GetPermissions(db)).Methods("GET")
+	router.HandleFunc("/admin/roles", controllers.GetRoles(db)).Methods("GET")
+	router.HandleFunc("/admin/roles", controllers.CreateRole(db)).Methods("POST")
+	router.HandleFunc("/admin/roles/{roleId}", controllers.UpdateRole(db)).Methods("PUT")
+	router.HandleFunc("/admin/roles/{roleId}", controllers.DeleteRole(db)).Methods("DELETE")
 
 	// Category routes
 	router.HandleFunc("/categories", controllers.CreateCategory(db)).Methods("POST")
@@ -25,12 +70,86 @@ func RegisterRoutes(router *mux.Router, db *gorm.DB) {
 	router.HandleFunc("/categories/{categoryId}", controllers.UpdateCategory(db)).Methods("PATCH")
 	router.HandleFunc("/categories/{categoryId}", controllers.DeleteCategory(db)).Methods("DELETE")
 
+	// Label routes
+	router.HandleFunc("/labels", controllers.GetLabels(db)).Methods("GET")
+	router.HandleFunc("/labels", controllers.CreateLabel(db)).Methods("POST")
+
+	// Board import and export routes
+	router.HandleFunc("/boards/export", controllers.ExportBoard(db)).Methods("GET")
+	router.HandleFunc("/boards/import", controllers.ImportBoard(db)).Methods("POST")
+	router.HandleFunc("/boards/import/trello", controllers.ImportTrelloBoard(db)).Methods("POST")
+
 	// Task routes
 	router.HandleFunc("/tasks", controllers.CreateTask(db)).Methods("POST")
+	router.HandleFunc("/tasks/bulk", controllers.BulkUpdateTasks(db)).Methods("POST")
 	router.HandleFunc("/tasks/{taskId}", controllers.UpdateTask(db)).Methods("PUT")
 	router.HandleFunc("/tasks/update-status/{taskId}", controllers.UpdateTaskStatus(db)).Methods("PATCH")
 	router.HandleFunc("/tasks/update-category/{taskId}", controllers.UpdateTaskCategory(db)).Methods("PATCH")
 	router.HandleFunc("/tasks/{taskId}", controllers.DeleteTask(db)).Methods("DELETE")
 	router.HandleFunc("/tasks", controllers.GetTasks(db)).Methods("GET")
 
+	// Saved view routes
+	router.HandleFunc("/views", controllers.GetSavedViews(db)).Methods("GET")
+	router.HandleFunc("/views", controllers.CreateSavedView(db)).Methods("POST")
+	router.HandleFunc("/views/{viewId}", controllers.UpdateSavedView(db)).Methods("PUT")
+	router.HandleFunc("/views/{viewId}", controllers.DeleteSavedView(db)).Methods("DELETE")
+
+	// Comment routes
+	router.HandleFunc("/tasks/{taskId}/comments", controllers.CreateComment(db)).Methods("POST")
+	router.HandleFunc("/tasks/{taskId}/comments", controllers.GetComments(db)).Methods("GET")
+
+	// Task template routes
+	router.HandleFunc("/task-templates", controllers.GetTaskTemplates(db)).Methods("GET")
+	router.HandleFunc("/task-templates", controllers.CreateTaskTemplate(db)).Methods("POST")
+	router.HandleFunc("/task-templates/{templateId}", controllers.GetTaskTemplate(db)).Methods("GET")
+	router.HandleFunc("/task-templates/{templateId}", controllers.UpdateTaskTemplate(db)).Methods("PUT")
+	router.HandleFunc("/task-templates/{templateId}", controllers.DeleteTaskTemplate(db)).Methods("DELETE")
+
+	// Recurring task routes
+	router.HandleFunc("/recurring-tasks", controllers.GetRecurringTasks(db)).Methods("GET")
+	router.HandleFunc("/recurring-tasks", controllers.CreateRecurringTask(db)).Methods("POST")
+	router.HandleFunc("/recurring-tasks/{recurringTaskId}", controllers.GetRecurringTask(db)).Methods("GET")
+	router.HandleFunc("/recurring-tasks/{recurringTaskId}", controllers.UpdateRecurringTask(db)).Methods("PUT")
+	router.HandleFunc("/recurring-tasks/{recurringTaskId}", controllers.DeleteRecurringTask(db)).Methods("DELETE")
+	router.HandleFunc("/recurring-tasks/{recurringTaskId}/pause", controllers.PauseRecurringTask(db)).Methods("POST")
+	router.HandleFunc("/recurring-tasks/{recurringTaskId}/resume", controllers.ResumeRecurringTask(db)).Methods("POST")
+
+	// Time tracking routes
+	router.HandleFunc("/tasks/{taskId}/timer/start", controllers.StartTimer(db)).Methods("POST")
+	router.HandleFunc("/tasks/{taskId}/timer/stop", controllers.StopTimer(db)).Methods("POST")
+	router.HandleFunc("/tasks/{taskId}/time-entries", controllers.CreateTimeEntry(db)).Methods("POST")
+	router.HandleFunc("/tasks/{taskId}/time-entries", controllers.GetTimeEntries(db)).Methods("GET")
+	router.HandleFunc("/time-entries/{entryId}", controllers.UpdateTimeEntry(db)).Methods("PUT")
+	router.HandleFunc("/time-entries/{entryId}", controllers.DeleteTimeEntry(db)).Methods("DELETE")
+	router.HandleFunc("/reports/time", controllers.GetTimeReport(db)).Methods("GET")
+
+	// Calendar routes
+	router.HandleFunc("/calendar/{token}.ics", controllers.GetCalendarFeedEvents(db)).Methods("GET")
+
+	// Search routes
+	router.HandleFunc("/search", controllers.Search(db)).Methods("GET")
+
+	// Sprint routes
+	router.HandleFunc("/sprints", controllers.GetSprints(db)).Methods("GET")
+	router.HandleFunc("/sprints", controllers.CreateSprint(db)).Methods("POST")
+	router.HandleFunc("/sprints/velocity", controllers.GetVelocity(db)).Methods("GET")
+	router.HandleFunc("/sprints/{sprintId}", controllers.GetSprint(db)).Methods("GET")
+	router.HandleFunc("/sprints/{sprintId}", controllers.UpdateSprint(db)).Methods("PUT")
+	router.HandleFunc("/sprints/{sprintId}", controllers.DeleteSprint(db)).Methods("DELETE")
+	router.HandleFunc("/sprints/{sprintId}/tasks", controllers.AddSprintTasks(db)).Methods("POST")
+	router.HandleFunc("/sprints/{sprintId}/tasks/{taskId}", controllers.RemoveSprintTask(db)).Methods("DELETE")
+	router.HandleFunc("/sprints/{sprintId}/burndown", controllers.GetSprintBurndown(db)).Methods("GET")
+
+	// Analytics routes
+	router.HandleFunc("/analytics/cumulative-flow", controllers.GetCumulativeFlow(db)).Methods("GET")
+	router.HandleFunc("/analytics/cycle-time", controllers.GetCycleTime(db)).Methods("GET")
+	router.HandleFunc("/analytics/throughput", controllers.GetThroughput(db)).Methods("GET")
+	router.HandleFunc("/analytics/aging-wip", controllers.GetAgingWIP(db)).Methods("GET")
+
+	// Notification routes
+	router.HandleFunc("/notifications", controllers.GetNotifications(db)).Methods("GET")
+	router.HandleFunc("/notifications/unread-count", controllers.GetUnreadNotificationCount(db)).Methods("GET")
+	router.HandleFunc("/notifications/read-all", controllers.MarkAllNotificationsRead(db)).Methods("PATCH")
+	router.HandleFunc("/notifications/{notificationId}/read", controllers.MarkNotificationRead(db)).Methods("PATCH")
+
 }
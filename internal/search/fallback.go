@@ -0,0 +1,242 @@
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxFallbackCandidates caps the tasks and the comments the fallback search
+// ranks in memory. Results.Truncated is set when a cap is reached, Total then
+// only counts the ranked rows.
+const maxFallbackCandidates = 1000
+
+// likeEscaper escapes the LIKE wildcards in a term, for patterns used with
+// ESCAPE '\'
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// snippetRadius is how many bytes of text are kept around the first match
+const snippetRadius = 60
+
+// searchFallback matches every word of the query as a substring, ignoring
+// case. Ranking, snippets and facets are worked out in Go.
+func searchFallback(db *gorm.DB, query Query) (*Results, error) {
+	terms := queryTerms(query.Text)
+	results := &Results{Hits: []Hit{}, Facets: Facets{Categories: []CategoryFacet{}, Status: []StatusFacet{}}}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	visible := func(q *gorm.DB) *gorm.DB {
+		if !query.AllTasks {
+			q = q.Where("(tasks.user_id = ? OR tasks.assignee_id = ?)", query.UserID, query.UserID)
+		}
+		return q
+	}
+
+	taskQuery := visible(db.Model(&models.Task{}))
+	for _, term := range terms {
+		pattern := "%" + likeEscaper.Replace(term) + "%"
+		taskQuery = taskQuery.Where(`(LOWER(tasks.title) LIKE ? ESCAPE '\' OR LOWER(tasks.description) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	var tasks []models.Task
+	if err := taskQuery.Limit(maxFallbackCandidates).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+
+	commentQuery := visible(db.Model(&models.Comment{}).Joins("JOIN tasks ON tasks.id = comments.task_id"))
+	for _, term := range terms {
+		commentQuery = commentQuery.Where(`LOWER(comments.body) LIKE ? ESCAPE '\'`, "%"+likeEscaper.Replace(term)+"%")
+	}
+	var comments []struct {
+		models.Comment
+		Title      string
+		CategoryID uint
+		Status     bool
+	}
+	if err := commentQuery.Select("comments.*, tasks.title, tasks.category_id, tasks.status").
+		Limit(maxFallbackCandidates).Find(&comments).Error; err != nil {
+		return nil, err
+	}
+
+	var hits []Hit
+	for _, task := range tasks {
+		text := task.Title + " " + task.Description
+		hits = append(hits, Hit{
+			Type:       "task",
+			TaskID:     task.ID,
+			Title:      task.Title,
+			CategoryID: task.CategoryID,
+			Status:     task.Status,
+			Rank:       rank(text, terms) + rank(task.Title, terms),
+			Snippet:    snippet(text, terms),
+		})
+	}
+	for _, comment := range comments {
+		commentID := comment.ID
+		hits = append(hits, Hit{
+			Type:       "comment",
+			TaskID:     comment.TaskID,
+			CommentID:  &commentID,
+			Title:      comment.Title,
+			CategoryID: comment.CategoryID,
+			Status:     comment.Status,
+			Rank:       rank(comment.Body, terms),
+			Snippet:    snippet(comment.Body, terms),
+		})
+	}
+
+	results.Facets = facets(hits)
+	results.Truncated = len(tasks) == maxFallbackCandidates || len(comments) == maxFallbackCandidates
+
+	var filtered []Hit
+	for _, hit := range hits {
+		if query.CategoryID != 0 && hit.CategoryID != query.CategoryID {
+			continue
+		}
+		if query.Status != nil && hit.Status != *query.Status {
+			continue
+		}
+		filtered = append(filtered, hit)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if filtered[i].Rank != filtered[j].Rank {
+			return filtered[i].Rank > filtered[j].Rank
+		}
+		return filtered[i].TaskID > filtered[j].TaskID
+	})
+
+	results.Total = int64(len(filtered))
+	start := (query.Page - 1) * query.Limit
+	if start < len(filtered) {
+		end := start + query.Limit
+		if end > len(filtered) {
+			end = len(filtered)
+		}
+		results.Hits = filtered[start:end]
+	}
+
+	return results, nil
+}
+
+// queryTerms splits the query into lower case words, dropping the quotes,
+// OR and excluded words Postgres would understand
+func queryTerms(text string) []string {
+	var terms []string
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		word = strings.Trim(word, `"`)
+		if word != "" && word != "or" {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// rank is the number of matches per 100 bytes of text
+func rank(text string, terms []string) float64 {
+	lower := strings.ToLower(text)
+	matches := 0
+	for _, term := range terms {
+		matches += strings.Count(lower, term)
+	}
+	return float64(matches) * 100 / float64(len(text)+100)
+}
+
+// snippet cuts the text around the first match and marks every match
+func snippet(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		// A few characters change length in lower case, then only exact
+		// matches are marked so the positions stay the same
+		lower = text
+	}
+	first := -1
+	for _, term := range terms {
+		if i := strings.Index(lower, term); i >= 0 && (first < 0 || i < first) {
+			first = i
+		}
+	}
+	if first < 0 {
+		first = 0
+	}
+
+	start, end := first-snippetRadius, first+snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+	// Do not cut a character in half
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	var marked strings.Builder
+	part, lowerPart := text[start:end], lower[start:end]
+	for i := 0; i < len(part); {
+		matched := 0
+		for _, term := range terms {
+			if strings.HasPrefix(lowerPart[i:], term) && len(term) > matched {
+				matched = len(term)
+			}
+		}
+		if matched > 0 {
+			marked.WriteString(startMark + part[i:i+matched] + stopMark)
+			i += matched
+			continue
+		}
+		marked.WriteByte(part[i])
+		i++
+	}
+
+	result := highlight(marked.String())
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(text) {
+		result += "…"
+	}
+	return result
+}
+
+// facets counts the distinct tasks of the hits by category and status
+func facets(hits []Hit) Facets {
+	seen := map[uint]bool{}
+	categories := map[uint]int64{}
+	status := map[bool]int64{}
+	for _, hit := range hits {
+		if seen[hit.TaskID] {
+			continue
+		}
+		seen[hit.TaskID] = true
+		categories[hit.CategoryID]++
+		status[hit.Status]++
+	}
+
+	result := Facets{Categories: []CategoryFacet{}, Status: []StatusFacet{}}
+	for id, count := range categories {
+		result.Categories = append(result.Categories, CategoryFacet{CategoryID: id, Count: count})
+	}
+	sort.Slice(result.Categories, func(i, j int) bool {
+		if result.Categories[i].Count != result.Categories[j].Count {
+			return result.Categories[i].Count > result.Categories[j].Count
+		}
+		return result.Categories[i].CategoryID < result.Categories[j].CategoryID
+	})
+	for _, done := range []bool{false, true} {
+		if count, ok := status[done]; ok {
+			result.Status = append(result.Status, StatusFacet{Status: done, Count: count})
+		}
+	}
+	return result
+}
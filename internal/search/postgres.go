@@ -0,0 +1,82 @@
+package search
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// The vectors must match the index expressions exactly for the GIN indexes
+// to be used
+func taskVector(table string) string {
+	return fmt.Sprintf("to_tsvector('%s', coalesce(%s.title, '') || ' ' || coalesce(%s.description, ''))", textConfig, table, table)
+}
+
+func commentVector(table string) string {
+	return fmt.Sprintf("to_tsvector('%s', coalesce(%s.body, ''))", textConfig, table)
+}
+
+func headline(text string) string {
+	return fmt.Sprintf("ts_headline('%s', %s, q.query, 'StartSel=%s, StopSel=%s, MaxFragments=2, MaxWords=20, MinWords=5')", textConfig, text, startMark, stopMark)
+}
+
+func searchPostgres(db *gorm.DB, query Query) (*Results, error) {
+	visible := "TRUE"
+	if !query.AllTasks {
+		visible = "(tasks.user_id = @user OR tasks.assignee_id = @user)"
+	}
+
+	// hits holds every match the user can see, before the category and
+	// status filters
+	hits := `WITH q AS (SELECT websearch_to_tsquery('` + textConfig + `', @text) AS query),
+hits AS (
+	SELECT 'task' AS type, tasks.id AS task_id, NULL::bigint AS comment_id, tasks.title, tasks.category_id, tasks.status,
+		ts_rank(` + taskVector("tasks") + `, q.query) AS rank,
+		` + headline("coalesce(tasks.title, '') || ' ' || coalesce(tasks.description, '')") + ` AS snippet
+	FROM tasks, q
+	WHERE ` + visible + ` AND ` + taskVector("tasks") + ` @@ q.query
+	UNION ALL
+	SELECT 'comment', tasks.id, comments.id, tasks.title, tasks.category_id, tasks.status,
+		ts_rank(` + commentVector("comments") + `, q.query),
+		` + headline("coalesce(comments.body, '')") + `
+	FROM comments JOIN tasks ON tasks.id = comments.task_id, q
+	WHERE ` + visible + ` AND ` + commentVector("comments") + ` @@ q.query
+)
+`
+	filter := "WHERE (@category = 0 OR category_id = @category) AND (@any_status OR status = @status)"
+
+	params := map[string]interface{}{
+		"text":       query.Text,
+		"user":       query.UserID,
+		"category":   query.CategoryID,
+		"any_status": query.Status == nil,
+		"status":     query.Status != nil && *query.Status,
+		"limit":      query.Limit,
+		"offset":     (query.Page - 1) * query.Limit,
+	}
+
+	results := &Results{Hits: []Hit{}, Facets: Facets{Categories: []CategoryFacet{}, Status: []StatusFacet{}}}
+
+	if err := db.Raw(hits+"SELECT COUNT(*) FROM hits "+filter, params).Scan(&results.Total).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Raw(hits+"SELECT * FROM hits "+filter+" ORDER BY rank DESC, task_id DESC, comment_id DESC LIMIT @limit OFFSET @offset", params).
+		Scan(&results.Hits).Error; err != nil {
+		return nil, err
+	}
+	for i := range results.Hits {
+		results.Hits[i].Snippet = highlight(results.Hits[i].Snippet)
+	}
+
+	if err := db.Raw(hits+"SELECT category_id, COUNT(DISTINCT task_id) AS count FROM hits GROUP BY category_id ORDER BY count DESC", params).
+		Scan(&results.Facets.Categories).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Raw(hits+"SELECT status, COUNT(DISTINCT task_id) AS count FROM hits GROUP BY status ORDER BY status", params).
+		Scan(&results.Facets.Status).Error; err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,142 @@
+package search
+
+import (
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// textConfig is the Postgres text search configuration. "simple" does no
+// stemming, so it works the same for every language.
+const textConfig = "simple"
+
+// Markers put around matches before the snippet is escaped, replaced by
+// <mark> tags afterwards
+const (
+	startMark = "\x01"
+	stopMark  = "\x02"
+)
+
+// Query is a search made by a user
+type Query struct {
+	Text   string
+	UserID uint
+	// AllTasks searches every task instead of only those the user owns or
+	// is assigned to
+	AllTasks   bool
+	CategoryID uint
+	Status     *bool
+	Page       int
+	Limit      int
+}
+
+// Hit is a task or comment matching the query. Snippet is HTML-escaped
+// text with the matches wrapped in <mark> tags.
+type Hit struct {
+	Type       string  `json:"type"`
+	TaskID     uint    `json:"task_id"`
+	CommentID  *uint   `json:"comment_id,omitempty"`
+	Title      string  `json:"title"`
+	CategoryID uint    `json:"category_id"`
+	Status     bool    `json:"status"`
+	Rank       float64 `json:"rank"`
+	Snippet    string  `json:"snippet"`
+}
+
+type CategoryFacet struct {
+	CategoryID uint   `json:"category_id"`
+	Type       string `json:"type"`
+	Count      int64  `json:"count"`
+}
+
+type StatusFacet struct {
+	Status bool  `json:"status"`
+	Count  int64 `json:"count"`
+}
+
+// Facets count the matching tasks by category and by status, ignoring the
+// category and status filters of the query
+type Facets struct {
+	Categories []CategoryFacet `json:"categories"`
+	Status     []StatusFacet   `json:"status"`
+}
+
+type Results struct {
+	Hits  []Hit `json:"hits"`
+	Total int64 `json:"total"`
+	// Truncated is set when the substring fallback stopped ranking at its
+	// cap, so Total and the facets leave out further matches
+	Truncated bool   `json:"truncated"`
+	Page      int    `json:"page"`
+	Limit     int    `json:"limit"`
+	Facets    Facets `json:"facets"`
+}
+
+// Search finds the tasks and comments matching the query, best match first.
+// Postgres uses full-text search, other databases a slower substring match.
+func Search(db *gorm.DB, query Query) (*Results, error) {
+	var results *Results
+	var err error
+	if db.Dialector.Name() == "postgres" {
+		results, err = searchPostgres(db, query)
+	} else {
+		results, err = searchFallback(db, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	results.Page = query.Page
+	results.Limit = query.Limit
+	if err := nameCategories(db, results.Facets.Categories); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// EnsureIndexes creates the GIN indexes used by the Postgres search. Other
+// databases have nothing to create.
+func EnsureIndexes(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, statement := range []string{
+		"CREATE INDEX IF NOT EXISTS idx_tasks_search ON tasks USING GIN (" + taskVector("tasks") + ")",
+		"CREATE INDEX IF NOT EXISTS idx_comments_search ON comments USING GIN (" + commentVector("comments") + ")",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func nameCategories(db *gorm.DB, facets []CategoryFacet) error {
+	if len(facets) == 0 {
+		return nil
+	}
+	var categories []struct {
+		ID   uint
+		Type string
+	}
+	if err := db.Table("categories").Select("id, type").Find(&categories).Error; err != nil {
+		return err
+	}
+	names := map[uint]string{}
+	for _, category := range categories {
+		names[category.ID] = category.Type
+	}
+	for i := range facets {
+		facets[i].Type = names[facets[i].CategoryID]
+	}
+	return nil
+}
+
+// highlight escapes a snippet holding match markers and turns the markers
+// into <mark> tags
+func highlight(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, startMark, "<mark>")
+	return strings.ReplaceAll(escaped, stopMark, "</mark>")
+}
@@ -0,0 +1,143 @@
+package timetracking
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/csvsafe"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	GroupByUser     = "user"
+	GroupByCategory = "category"
+	GroupByDate     = "date"
+)
+
+// ValidGroupBy reports whether a report can be grouped by groupBy
+func ValidGroupBy(groupBy string) bool {
+	return groupBy == GroupByUser || groupBy == GroupByCategory || groupBy == GroupByDate
+}
+
+// ReportQuery selects the entries started between From and To. Without
+// AllUsers only the entries of UserID are used.
+type ReportQuery struct {
+	UserID     uint
+	AllUsers   bool
+	From       time.Time
+	To         time.Time
+	GroupBy    string
+	CategoryID *uint
+}
+
+type ReportRow struct {
+	// Group is the user or category ID, or the date
+	Group   string  `json:"group"`
+	Name    string  `json:"name"`
+	Seconds int64   `json:"seconds"`
+	Hours   float64 `json:"hours"`
+	Entries int     `json:"entries"`
+}
+
+type Report struct {
+	From         time.Time   `json:"from"`
+	To           time.Time   `json:"to"`
+	GroupBy      string      `json:"group_by"`
+	Rows         []ReportRow `json:"rows"`
+	TotalSeconds int64       `json:"total_seconds"`
+	TotalHours   float64     `json:"total_hours"`
+}
+
+// BuildReport sums up the time of the entries by user, category or date
+func BuildReport(db *gorm.DB, query ReportQuery) (*Report, error) {
+	entriesQuery := db.Preload("User").Preload("Task").
+		Where("started_at >= ? AND started_at <= ?", query.From, query.To)
+	if !query.AllUsers {
+		entriesQuery = entriesQuery.Where("user_id = ?", query.UserID)
+	}
+	if query.CategoryID != nil {
+		entriesQuery = entriesQuery.Where("task_id IN (SELECT id FROM tasks WHERE category_id = ?)", *query.CategoryID)
+	}
+	var entries []models.TimeEntry
+	if err := entriesQuery.Order("started_at, id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	categoryNames := map[uint]string{}
+	if query.GroupBy == GroupByCategory {
+		var categories []models.Category
+		if err := db.Find(&categories).Error; err != nil {
+			return nil, err
+		}
+		for _, category := range categories {
+			categoryNames[category.ID] = category.Type
+		}
+	}
+
+	now := time.Now()
+	rows := map[string]*ReportRow{}
+	var order []string
+	report := &Report{From: query.From, To: query.To, GroupBy: query.GroupBy, Rows: []ReportRow{}}
+	for _, entry := range entries {
+		var group, name string
+		switch query.GroupBy {
+		case GroupByUser:
+			group, name = fmt.Sprint(entry.UserID), entry.User.FullName
+		case GroupByCategory:
+			group, name = fmt.Sprint(entry.Task.CategoryID), categoryNames[entry.Task.CategoryID]
+		case GroupByDate:
+			group = entry.StartedAt.Format("2006-01-02")
+			name = group
+		}
+
+		row, ok := rows[group]
+		if !ok {
+			row = &ReportRow{Group: group, Name: name}
+			rows[group] = row
+			order = append(order, group)
+		}
+		seconds := int64(Duration(entry, now).Seconds())
+		row.Seconds += seconds
+		row.Entries++
+		report.TotalSeconds += seconds
+	}
+
+	// Dates read in order, users and categories most time first
+	if query.GroupBy != GroupByDate {
+		sort.SliceStable(order, func(i, j int) bool { return rows[order[i]].Seconds > rows[order[j]].Seconds })
+	}
+	for _, group := range order {
+		row := rows[group]
+		row.Hours = hours(row.Seconds)
+		report.Rows = append(report.Rows, *row)
+	}
+	report.TotalHours = hours(report.TotalSeconds)
+
+	return report, nil
+}
+
+// WriteCSV writes the rows of a report, the first column named after what
+// the report is grouped by. Cells are escaped for spreadsheets.
+func WriteCSV(w io.Writer, report *Report) error {
+	writer := csvsafe.NewWriter(w)
+	if err := writer.Write([]string{report.GroupBy, "name", "hours", "entries"}); err != nil {
+		return err
+	}
+	for _, row := range report.Rows {
+		record := []string{row.Group, row.Name, strconv.FormatFloat(row.Hours, 'f', 2, 64), strconv.Itoa(row.Entries)}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func hours(seconds int64) float64 {
+	return math.Round(float64(seconds)/3600*100) / 100
+}
@@ -0,0 +1,95 @@
+// Package timetracking records the time users spend on tasks, with timers
+// or logged entries, and sums it up in reports.
+package timetracking
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrNoTimer = errors.New("No timer is running on this task")
+
+// Duration is the time spent in an entry, up to now for a running timer
+func Duration(entry models.TimeEntry, now time.Time) time.Duration {
+	if entry.EndedAt != nil {
+		return entry.EndedAt.Sub(entry.StartedAt)
+	}
+	return now.Sub(entry.StartedAt)
+}
+
+// EnsureIndexes creates the unique index that allows one running timer per
+// user. Timers left running next to a newer one are stopped first.
+func EnsureIndexes(db *gorm.DB) error {
+	if err := db.Model(&models.TimeEntry{}).
+		Where("ended_at IS NULL AND id NOT IN (?)", db.Model(&models.TimeEntry{}).Select("MAX(id)").Where("ended_at IS NULL").Group("user_id")).
+		Update("ended_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_time_entries_running ON time_entries (user_id) WHERE ended_at IS NULL").Error
+}
+
+// Start starts a timer of the user on a task. A user has one timer at a
+// time, so a timer running on another task is stopped. The user row is
+// locked so parallel starts wait for each other instead of failing on the
+// running timer index.
+func Start(db *gorm.DB, userID, taskID uint, note string) (*models.TimeEntry, error) {
+	entry := &models.TimeEntry{TaskID: taskID, UserID: userID, Note: note}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.TimeEntry{}).Where("user_id = ? AND ended_at IS NULL", userID).Update("ended_at", now).Error; err != nil {
+			return err
+		}
+		entry.StartedAt = now
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Stop stops the timer of the user on a task
+func Stop(db *gorm.DB, userID, taskID uint) (*models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := db.Where("user_id = ? AND task_id = ? AND ended_at IS NULL", userID, taskID).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNoTimer
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	entry.EndedAt = &now
+	if err := db.Model(&entry).Update("ended_at", now).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Totals sums the time spent on each task, counting running timers up to now
+func Totals(db *gorm.DB, taskIDs []uint) (map[uint]time.Duration, error) {
+	totals := map[uint]time.Duration{}
+	if len(taskIDs) == 0 {
+		return totals, nil
+	}
+
+	var entries []models.TimeEntry
+	if err := db.Select("task_id, started_at, ended_at").Where("task_id IN ?", taskIDs).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		totals[entry.TaskID] += Duration(entry, now)
+	}
+	return totals, nil
+}
@@ -4,21 +4,52 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/accounts"
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/config"
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/controllers"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/notifications"
+	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/recurrence"
 	"github.com/Hafidzurr/project3_group2_glng-ks-08/internal/routes"
 	"github.com/gorilla/mux"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		if err := runAdminCommand(os.Args[2:]); err != nil {
+			log.Fatalf("Error: %v\n", err)
+		}
+		return
+	}
+
+	if err := config.LoadSigningKeys(); err != nil {
+		log.Fatalf("Error loading JWT keys: %v\n", err)
+	}
+	if err := config.CheckProductionSettings(); err != nil {
+		log.Fatalf("Refusing to start: %v\n", err)
+	}
+
 	db, err := config.ConnectDB()
 	if err != nil {
 		log.Fatalf("Error connecting to database: %v\n", err)
 	}
 
-	// Initialize Admin User
-	controllers.InitializeAdminUser(db)
+	// Make sure no admin is left with the old default password
+	controllers.SecureLegacyAdmin(db)
+	if !controllers.AdminExists(db) {
+		log.Println("No admin account exists, create one with `go run . admin create` or POST /setup with SETUP_TOKEN")
+	}
+
+	// Start sending email notifications in the background
+	notifications.Start(notifications.NewMailerFromEnv())
+	notifications.StartDueSoonScheduler(db, 15*time.Minute, 24*time.Hour)
+
+	// Delete accounts whose grace period is over and expired data exports
+	accounts.StartScheduler(db, time.Hour)
+
+	// Create the tasks of recurring series when they are due
+	recurrence.StartScheduler(db, time.Minute)
 
 	router := mux.NewRouter()
 	routes.RegisterRoutes(router, db)